@@ -30,7 +30,6 @@ var embeddedLocales embed.FS
 
 func main() {
 	var err error
-	core.NewStandardApx()
 
 	// Initialize SDK App
 	subFS, err := fs.Sub(embeddedLocales, "locales")
@@ -51,6 +50,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	_, err = core.NewStandardApx()
+	if err != nil {
+		os.Exit(cmd.ReportError(err))
+	}
+
 	// Initialize CLI
 	rootCmdStruct := &cmd.RootCmd{
 		Version: Version,
@@ -61,25 +65,31 @@ func main() {
 	if err == nil {
 		m := make(map[string]*cmd.SubsystemCmd)
 		rootCmdStruct.DynamicSubsystems = &m
+		names := []string{}
 		for _, s := range subSystems {
-			(*rootCmdStruct.DynamicSubsystems)[s.Name] = &cmd.SubsystemCmd{
-				Name:       s.Name,
-				Enter:      cmd.SubsystemEnterCmd{Name: s.Name},
-				Run:        cmd.SubsystemRunCmd{Name: s.Name},
-				Install:    cmd.SubsystemInstallCmd{Name: s.Name},
-				Remove:     cmd.SubsystemRemoveCmd{Name: s.Name},
-				Update:     cmd.SubsystemUpdateCmd{Name: s.Name},
-				Upgrade:    cmd.SubsystemUpgradeCmd{Name: s.Name},
-				List:       cmd.SubsystemListCmd{Name: s.Name},
-				Search:     cmd.SubsystemSearchCmd{Name: s.Name},
-				Show:       cmd.SubsystemShowCmd{Name: s.Name},
-				Export:     cmd.SubsystemExportCmd{Name: s.Name},
-				Unexport:   cmd.SubsystemUnexportCmd{Name: s.Name},
-				Start:      cmd.SubsystemStartCmd{Name: s.Name},
-				Stop:       cmd.SubsystemStopCmd{Name: s.Name},
-				AutoRemove: cmd.SubsystemAutoRemoveCmd{Name: s.Name},
-				Clean:      cmd.SubsystemCleanCmd{Name: s.Name},
-				Purge:      cmd.SubsystemPurgeCmd{Name: s.Name},
+			(*rootCmdStruct.DynamicSubsystems)[s.Name] = newSubsystemCmd(s.Name)
+			names = append(names, s.Name)
+		}
+		cmd.WarnShadowedSubSystems(names)
+
+		// Aliases and the default subsystem, targeted by the top-level
+		// shortcuts. Aliases never shadow a real subsystem.
+		preferences, err := core.LoadSubSystemPreferences()
+		if err == nil {
+			for alias, name := range preferences.Aliases {
+				if _, ok := m[alias]; ok {
+					continue
+				}
+				if _, ok := m[name]; ok {
+					m[alias] = newSubsystemCmd(name)
+				}
+			}
+
+			if _, ok := m[preferences.Default]; ok {
+				rootCmdStruct.Install = cmd.SubsystemInstallCmd{Name: preferences.Default}
+				rootCmdStruct.Remove = cmd.SubsystemRemoveCmd{Name: preferences.Default}
+				rootCmdStruct.Run = cmd.SubsystemRunCmd{Name: preferences.Default}
+				rootCmdStruct.Enter = cmd.SubsystemEnterCmd{Name: preferences.Default}
 			}
 		}
 	}
@@ -98,7 +108,51 @@ func main() {
 
 	err = cmd.Apx.CLI.Execute()
 	if err != nil {
-		cmd.Apx.Log.Error(err.Error())
-		os.Exit(1)
+		os.Exit(cmd.ReportError(err))
+	}
+}
+
+// newSubsystemCmd returns the commands operating on the named subsystem.
+func newSubsystemCmd(name string) *cmd.SubsystemCmd {
+	return &cmd.SubsystemCmd{
+		Name:       name,
+		Enter:      cmd.SubsystemEnterCmd{Name: name},
+		Run:        cmd.SubsystemRunCmd{Name: name},
+		Install:    cmd.SubsystemInstallCmd{Name: name},
+		Remove:     cmd.SubsystemRemoveCmd{Name: name},
+		Update:     cmd.SubsystemUpdateCmd{Name: name},
+		Upgrade:    cmd.SubsystemUpgradeCmd{Name: name},
+		List:       cmd.SubsystemListCmd{Name: name},
+		Search:     cmd.SubsystemSearchCmd{Name: name},
+		Show:       cmd.SubsystemShowCmd{Name: name},
+		Export:     cmd.SubsystemExportCmd{Name: name},
+		Unexport:   cmd.SubsystemUnexportCmd{Name: name},
+		Start:      cmd.SubsystemStartCmd{Name: name},
+		Stop:       cmd.SubsystemStopCmd{Name: name},
+		AutoRemove: cmd.SubsystemAutoRemoveCmd{Name: name},
+		Clean:      cmd.SubsystemCleanCmd{Name: name},
+		Purge:      cmd.SubsystemPurgeCmd{Name: name},
+
+		Hold:           cmd.SubsystemHoldCmd{Name: name},
+		Unhold:         cmd.SubsystemUnholdCmd{Name: name},
+		ListUpgradable: cmd.SubsystemListUpgradableCmd{Name: name},
+		RepoAdd:        cmd.SubsystemRepoAddCmd{Name: name},
+		RepoRemove:     cmd.SubsystemRepoRemoveCmd{Name: name},
+		Owns:           cmd.SubsystemOwnsCmd{Name: name},
+
+		Undo: cmd.SubsystemUndoCmd{Name: name},
+		Diff: cmd.SubsystemDiffCmd{Name: name},
+		Sync: cmd.SubsystemSyncCmd{Name: name},
+
+		Mounts: cmd.SubsystemMountsCmd{
+			List:   cmd.SubsystemMountsListCmd{Name: name},
+			Add:    cmd.SubsystemMountsAddCmd{Name: name},
+			Remove: cmd.SubsystemMountsRemoveCmd{Name: name},
+		},
+		Env: cmd.SubsystemEnvCmd{
+			List:  cmd.SubsystemEnvListCmd{Name: name},
+			Set:   cmd.SubsystemEnvSetCmd{Name: name},
+			Unset: cmd.SubsystemEnvUnsetCmd{Name: name},
+		},
 	}
 }
@@ -10,8 +10,6 @@ package core
 */
 
 import (
-	"fmt"
-
 	"github.com/vanilla-os/apx/v3/settings"
 )
 
@@ -21,36 +19,44 @@ type Apx struct {
 	Cnf *settings.Config
 }
 
-func NewApx(cnf *settings.Config) *Apx {
+// NewApx initializes apx with the given configuration. The returned error
+// comes from the validation of the pull policy or from the essential
+// checks, e.g. ErrDistroboxNotFound.
+func NewApx(cnf *settings.Config) (*Apx, error) {
+	err := ValidatePullPolicy(cnf.PullPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	apx = &Apx{
 		Cnf: cnf,
 	}
 
-	err := apx.EssentialChecks()
+	err = apx.EssentialChecks()
 	if err != nil {
-		// localisation features aren't available at this stage, so this error can't be translated
-		fmt.Println("ERROR: Unable to find apx configuration files")
-		return nil
+		return nil, err
 	}
 
-	return apx
+	return apx, nil
 }
 
-func NewStandardApx() *Apx {
+// NewStandardApx initializes apx with the default configuration.
+func NewStandardApx() (*Apx, error) {
 	cnf, err := settings.GetApxDefaultConfig()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	apx = &Apx{
-		Cnf: cnf,
-	}
+	return NewApx(cnf)
+}
 
-	err = apx.EssentialChecks()
-	if err != nil {
-		// localisation features aren't available at this stage, so this error can't be translated
-		fmt.Println("ERROR: Unable to find apx configuration files")
-		return nil
-	}
-	return apx
+// SetAssumeYes enables or disables the non-interactive mode, making every
+// command generated by PkgManager.GenCmd answer yes to all prompts.
+func SetAssumeYes(assumeYes bool) {
+	apx.Cnf.AssumeYes = assumeYes
+}
+
+// AssumeYes reports whether the non-interactive mode is enabled.
+func AssumeYes() bool {
+	return apx.Cnf.AssumeYes
 }
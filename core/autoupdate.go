@@ -0,0 +1,304 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AutoUpdateUnitName is the name shared by the systemd user service and
+// timer running the scheduled subsystem updates.
+const AutoUpdateUnitName = "apx-autoupdate"
+
+// scheduleRegex matches the characters allowed in an OnCalendar value,
+// e.g. "daily", "Mon *-*-* 04:00:00" or "*-*-1/2 ~03".
+var scheduleRegex = regexp.MustCompile(`^[A-Za-z0-9 *,./:~-]+$`)
+
+// AutoUpdateResult represents the outcome of a scheduled update for a
+// subsystem, as stored in the autoupdate log.
+type AutoUpdateResult struct {
+	Subsystem string
+	Time      time.Time
+	Success   bool
+	Error     string `json:",omitempty"`
+}
+
+// AutoUpdateUnitsDir returns the directory where the systemd user units
+// are installed.
+func AutoUpdateUnitsDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "systemd", "user"), nil
+}
+
+// GenAutoUpdateUnits generates the systemd service and timer units which
+// run "apx autoupdate run" with the given schedule, an OnCalendar value
+// like "daily" or "weekly". An empty subSystem means all subsystems.
+func GenAutoUpdateUnits(apxBinary string, schedule string, subSystem string) (string, string, error) {
+	if strings.TrimSpace(schedule) == "" || !scheduleRegex.MatchString(schedule) {
+		return "", "", fmt.Errorf("invalid schedule: %q", schedule)
+	}
+
+	execStart := []string{quoteUnitArg(apxBinary), "autoupdate", "run", "--all"}
+	if subSystem != "" {
+		execStart = []string{quoteUnitArg(apxBinary), "autoupdate", "run", "--subsystem", quoteUnitArg(subSystem)}
+	}
+
+	service := fmt.Sprintf(`[Unit]
+Description=Update the apx subsystems
+
+[Service]
+Type=oneshot
+Environment=APX_ASSUME_YES=1
+ExecStart=%s
+`, strings.Join(execStart, " "))
+
+	timer := fmt.Sprintf(`[Unit]
+Description=Update the apx subsystems periodically
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+RandomizedDelaySec=15min
+
+[Install]
+WantedBy=timers.target
+`, schedule)
+
+	return service, timer, nil
+}
+
+// quoteUnitArg quotes arg for the command line of a systemd unit, escaping
+// the specifiers and the variables systemd would expand in it.
+func quoteUnitArg(arg string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$")
+	return `"` + replacer.Replace(arg) + `"`
+}
+
+// InstallAutoUpdateUnits writes the autoupdate units to unitsDir.
+func InstallAutoUpdateUnits(unitsDir string, apxBinary string, schedule string, subSystem string) error {
+	service, timer, err := GenAutoUpdateUnits(apxBinary, schedule, subSystem)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(unitsDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(unitsDir, AutoUpdateUnitName+".service"), []byte(service), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(unitsDir, AutoUpdateUnitName+".timer"), []byte(timer), 0644)
+}
+
+// RemoveAutoUpdateUnits removes the autoupdate units from unitsDir.
+func RemoveAutoUpdateUnits(unitsDir string) error {
+	for _, ext := range []string{".timer", ".service"} {
+		err := os.Remove(filepath.Join(unitsDir, AutoUpdateUnitName+ext))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateSchedule checks that schedule is a valid OnCalendar value, using
+// systemd-analyze when available.
+func ValidateSchedule(schedule string) error {
+	if strings.TrimSpace(schedule) == "" || !scheduleRegex.MatchString(schedule) {
+		return fmt.Errorf("invalid schedule: %q", schedule)
+	}
+
+	if _, err := exec.LookPath("systemd-analyze"); err != nil {
+		return nil
+	}
+
+	out, err := exec.Command("systemd-analyze", "calendar", schedule).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %s", schedule, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// AutoUpdateUnitsInstalled reports whether the autoupdate timer exists in
+// unitsDir.
+func AutoUpdateUnitsInstalled(unitsDir string) bool {
+	_, err := os.Stat(filepath.Join(unitsDir, AutoUpdateUnitName+".timer"))
+	return err == nil
+}
+
+// EnableAutoUpdate installs the autoupdate units in the user systemd
+// directory and starts the timer.
+func EnableAutoUpdate(schedule string, subSystem string) error {
+	err := ValidateSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	unitsDir, err := AutoUpdateUnitsDir()
+	if err != nil {
+		return err
+	}
+
+	apxBinary, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	err = InstallAutoUpdateUnits(unitsDir, apxBinary, schedule, subSystem)
+	if err != nil {
+		return err
+	}
+
+	err = systemctlUser("daemon-reload")
+	if err != nil {
+		return err
+	}
+
+	return systemctlUser("enable", "--now", AutoUpdateUnitName+".timer")
+}
+
+// DisableAutoUpdate stops the timer and removes the autoupdate units.
+func DisableAutoUpdate() error {
+	unitsDir, err := AutoUpdateUnitsDir()
+	if err != nil {
+		return err
+	}
+
+	if AutoUpdateUnitsInstalled(unitsDir) {
+		err = systemctlUser("disable", "--now", AutoUpdateUnitName+".timer")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = RemoveAutoUpdateUnits(unitsDir)
+	if err != nil {
+		return err
+	}
+
+	return systemctlUser("daemon-reload")
+}
+
+func systemctlUser(args ...string) error {
+	cmd := exec.Command("systemctl", append([]string{"--user"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl --user %s: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// AutoUpdate refreshes the package manager metadata and upgrades all the
+// packages of the subsystem without prompting.
+func (s *SubSystem) AutoUpdate() error {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return err
+	}
+
+	err = s.RecordTransaction(TransactionUpgrade, nil, func() error {
+		for _, op := range []string{PkgManagerOpUpdate, PkgManagerOpUpgrade} {
+			if !pkgManager.Supports(op) {
+				continue
+			}
+
+			cmd, _ := pkgManager.GetCommand(op)
+			_, err := s.Exec(true, false, pkgManager.genCmd(true, cmd)...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.MarkUpgraded()
+}
+
+func autoUpdateLogPath() string {
+	return filepath.Join(apx.Cnf.ApxStoragePath, "autoupdate.jsonl")
+}
+
+// LogAutoUpdateResult appends the result to the autoupdate log.
+func LogAutoUpdateResult(result AutoUpdateResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(autoUpdateLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ListAutoUpdateResults returns all the results stored in the autoupdate
+// log, oldest first. Malformed lines are skipped.
+func ListAutoUpdateResults() ([]AutoUpdateResult, error) {
+	results := []AutoUpdateResult{}
+
+	f, err := os.Open(autoUpdateLogPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return results, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var result AutoUpdateResult
+		if json.Unmarshal(scanner.Bytes(), &result) == nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, scanner.Err()
+}
+
+// LastAutoUpdateResults returns the most recent result for each subsystem.
+func LastAutoUpdateResults() (map[string]AutoUpdateResult, error) {
+	results, err := ListAutoUpdateResults()
+	if err != nil {
+		return nil, err
+	}
+
+	last := map[string]AutoUpdateResult{}
+	for _, result := range results {
+		last[result.Subsystem] = result
+	}
+
+	return last, nil
+}
@@ -0,0 +1,83 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallAutoUpdateUnits(t *testing.T) {
+	unitsDir := filepath.Join(t.TempDir(), "systemd", "user")
+
+	err := InstallAutoUpdateUnits(unitsDir, "/usr/bin/apx", "Mon *-*-* 04:00:00", "my-subsystem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !AutoUpdateUnitsInstalled(unitsDir) {
+		t.Fatal("timer not installed")
+	}
+
+	service, err := os.ReadFile(filepath.Join(unitsDir, AutoUpdateUnitName+".service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(service), `ExecStart="/usr/bin/apx" autoupdate run --subsystem "my-subsystem"`) {
+		t.Errorf("unexpected service unit:\n%s", service)
+	}
+
+	timer, err := os.ReadFile(filepath.Join(unitsDir, AutoUpdateUnitName+".timer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(timer), "OnCalendar=Mon *-*-* 04:00:00\n") {
+		t.Errorf("unexpected timer unit:\n%s", timer)
+	}
+
+	err = RemoveAutoUpdateUnits(unitsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if AutoUpdateUnitsInstalled(unitsDir) {
+		t.Error("timer still installed")
+	}
+}
+
+func TestGenAutoUpdateUnitsAll(t *testing.T) {
+	service, _, err := GenAutoUpdateUnits("/usr/bin/apx", "daily", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(service, "ExecStart=\"/usr/bin/apx\" autoupdate run --all\n") {
+		t.Errorf("unexpected service unit:\n%s", service)
+	}
+}
+
+func TestGenAutoUpdateUnitsQuoting(t *testing.T) {
+	service, _, err := GenAutoUpdateUnits("/opt/my apps/apx", "daily", `dev%i$HOME"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(service, `ExecStart="/opt/my apps/apx" autoupdate run --subsystem "dev%%i$$HOME\""`+"\n") {
+		t.Errorf("unexpected service unit:\n%s", service)
+	}
+}
+
+func TestGenAutoUpdateUnitsInvalidSchedule(t *testing.T) {
+	for _, schedule := range []string{"", " ", "daily\nExecStart=/bin/sh", "daily; rm -rf ~", `"weekly"`} {
+		_, _, err := GenAutoUpdateUnits("/usr/bin/apx", schedule, "")
+		if err == nil {
+			t.Errorf("schedule %q accepted", schedule)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// CommandSuggestionExport means the command is already available in the
+	// subsystem and only needs to be exported to the host.
+	CommandSuggestionExport = "export"
+
+	// CommandSuggestionInstall means the command is provided by a package
+	// which can be installed in the subsystem.
+	CommandSuggestionInstall = "install"
+)
+
+// CommandNotFoundCacheTTL is how long a lookup result is reused before the
+// subsystems are queried again.
+const CommandNotFoundCacheTTL = 24 * time.Hour
+
+// CommandSuggestion represents a way to make a missing command available
+// on the host through a subsystem.
+type CommandSuggestion struct {
+	Subsystem string
+	Stack     string
+	Action    string
+	Package   string
+}
+
+// commandNotFoundCacheEntry is a cached lookup, Search tells whether the
+// package managers were searched too.
+type commandNotFoundCacheEntry struct {
+	Time        time.Time
+	Search      bool
+	Suggestions []CommandSuggestion
+}
+
+// Command returns the apx command line applying the suggestion.
+func (c CommandSuggestion) Command(command string) string {
+	if c.Action == CommandSuggestionExport {
+		return fmt.Sprintf("apx %s export --bin %s", c.Subsystem, command)
+	}
+	return fmt.Sprintf("apx %s install %s", c.Subsystem, c.Package)
+}
+
+func commandNotFoundCachePath() string {
+	return filepath.Join(apx.Cnf.ApxStoragePath, "command-not-found.json")
+}
+
+func loadCommandNotFoundCache() map[string]commandNotFoundCacheEntry {
+	cache := map[string]commandNotFoundCacheEntry{}
+
+	data, err := os.ReadFile(commandNotFoundCachePath())
+	if err != nil {
+		return cache
+	}
+
+	// a corrupted cache is simply rebuilt
+	if json.Unmarshal(data, &cache) != nil {
+		return map[string]commandNotFoundCacheEntry{}
+	}
+
+	return cache
+}
+
+func saveCommandNotFoundCache(cache map[string]commandNotFoundCacheEntry) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	// the cache is replaced atomically, since the shell hooks of several
+	// terminals can read and write it at the same time
+	f, err := os.CreateTemp(filepath.Dir(commandNotFoundCachePath()), "command-not-found-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Chmod(0644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), commandNotFoundCachePath())
+}
+
+// ClearCommandNotFoundCache removes all the cached lookups, it should be
+// called whenever the packages or the exports of a subsystem change.
+func ClearCommandNotFoundCache() error {
+	err := os.Remove(commandNotFoundCachePath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// LookupCommand looks for the command in the running subsystems, returning
+// how it can be made available on the host. Commands already in a
+// subsystem are suggested for export. When search is true, all the
+// subsystems are queried, starting the stopped ones, and the package
+// managers are searched for a package with the same name. This is slow, so
+// the shell hooks do not do it. Results, including empty ones, are cached
+// for CommandNotFoundCacheTTL unless useCache is false.
+func LookupCommand(command string, useCache bool, search bool) ([]CommandSuggestion, error) {
+	cache := loadCommandNotFoundCache()
+	if useCache {
+		entry, ok := cache[command]
+		if ok && (entry.Search || !search) && time.Since(entry.Time) < CommandNotFoundCacheTTL {
+			return entry.Suggestions, nil
+		}
+	}
+
+	subSystems, err := ListSubSystems(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*CommandSuggestion, len(subSystems))
+
+	var wg sync.WaitGroup
+	for i, subSystem := range subSystems {
+		if !search && !subSystem.IsRunning() {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, subSystem *SubSystem) {
+			defer wg.Done()
+			found[i] = subSystem.lookupCommand(command, search)
+		}(i, subSystem)
+	}
+	wg.Wait()
+
+	suggestions := []CommandSuggestion{}
+	for _, suggestion := range found {
+		if suggestion != nil {
+			suggestions = append(suggestions, *suggestion)
+		}
+	}
+
+	cache[command] = commandNotFoundCacheEntry{
+		Time:        time.Now(),
+		Search:      search,
+		Suggestions: suggestions,
+	}
+	err = saveCommandNotFoundCache(cache)
+	if err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+func (s *SubSystem) lookupCommand(command string, search bool) *CommandSuggestion {
+	path, err := s.Which(command)
+	if err == nil && path != "" {
+		if s.IsBinaryExported(command) {
+			return nil
+		}
+
+		pkg, _ := s.Owner(path)
+		return &CommandSuggestion{
+			Subsystem: s.Name,
+			Stack:     s.Stack.Name,
+			Action:    CommandSuggestionExport,
+			Package:   pkg,
+		}
+	}
+
+	if !search {
+		return nil
+	}
+
+	results, err := s.Search(command)
+	if err != nil {
+		return nil
+	}
+
+	for _, result := range results {
+		if result.Name == command {
+			return &CommandSuggestion{
+				Subsystem: s.Name,
+				Stack:     s.Stack.Name,
+				Action:    CommandSuggestionInstall,
+				Package:   result.Name,
+			}
+		}
+	}
+
+	return nil
+}
+
+// GenCommandNotFoundHook returns the snippet which registers apx as the
+// command-not-found handler of the given shell (bash, zsh or fish). The
+// handler is skipped inside containers, where apx is not available.
+func GenCommandNotFoundHook(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return `command_not_found_handle() {
+    if [ -z "$CONTAINER_ID" ] && command -v apx >/dev/null 2>&1; then
+        apx command-not-found "$1"
+    else
+        printf "%s: command not found\n" "$1" >&2
+    fi
+    return 127
+}
+`, nil
+	case "zsh":
+		return `command_not_found_handler() {
+    if [ -z "$CONTAINER_ID" ] && command -v apx >/dev/null 2>&1; then
+        apx command-not-found "$1"
+    else
+        printf "zsh: command not found: %s\n" "$1" >&2
+    fi
+    return 127
+}
+`, nil
+	case "fish":
+		return `function fish_command_not_found
+    if test -z "$CONTAINER_ID"; and command -q apx
+        apx command-not-found $argv[1]
+    else
+        __fish_default_command_not_found_handler $argv
+    end
+end
+`, nil
+	}
+
+	return "", fmt.Errorf("unsupported shell: %s", shell)
+}
@@ -13,20 +13,29 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"os/exec"
 	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/vanilla-os/apx/v3/settings"
 )
 
+// snapshotImagePrefix is the prefix of the images created by committing a
+// subsystem container.
+const snapshotImagePrefix = "apx-snapshot-"
+
 type dbox struct {
 	Engine       string
 	EngineBinary string
 	Version      string
+
+	// output receives the output of the commands which would otherwise be
+	// printed, e.g. to parse it into progress events
+	output io.Writer
 }
 
 type dboxContainer struct {
@@ -37,6 +46,38 @@ type dboxContainer struct {
 	Names     []string          `json:"Names"`
 }
 
+// dboxContainerInspect holds the details of a container reported by
+// the engine. ImageName is only set by podman, docker reports the image
+// in Config.Image.
+type dboxContainerInspect struct {
+	Created time.Time `json:"Created"`
+	State   struct {
+		Status    string    `json:"Status"`
+		Running   bool      `json:"Running"`
+		StartedAt time.Time `json:"StartedAt"`
+	} `json:"State"`
+	Image     string `json:"Image"`
+	ImageName string `json:"ImageName"`
+	Config    struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+	SizeRw     int64 `json:"SizeRw"`
+	SizeRootFs int64 `json:"SizeRootFs"`
+}
+
+type dboxImageInspect struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+	Size        int64    `json:"Size"`
+	Config      struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	RootFS struct {
+		Layers []string `json:"Layers"`
+	} `json:"RootFS"`
+}
+
 type dockerContainer struct {
 	ID        string `json:"ID"`
 	CreatedAt string `json:"CreatedAt"`
@@ -46,7 +87,10 @@ type dockerContainer struct {
 }
 
 func NewDbox() (*dbox, error) {
-	engineBinary, engine := getEngine()
+	engineBinary, engine, err := getEngine()
+	if err != nil {
+		return nil, err
+	}
 
 	version, err := dboxGetVersion()
 	if err != nil {
@@ -60,19 +104,18 @@ func NewDbox() (*dbox, error) {
 	}, nil
 }
 
-func getEngine() (string, string) {
+func getEngine() (string, string, error) {
 	podmanBinary, err := settings.LookPath("podman")
 	if err == nil {
-		return podmanBinary, "podman"
+		return podmanBinary, "podman", nil
 	}
 
 	dockerBinary, err := settings.LookPath("docker")
 	if err == nil {
-		return dockerBinary, "docker"
+		return dockerBinary, "docker", nil
 	}
 
-	log.Fatal("no container engine found. Please install Podman or Docker.")
-	return "", ""
+	return "", "", ErrEngineNotFound
 }
 
 func dboxGetVersion() (version string, err error) {
@@ -141,13 +184,23 @@ func (d *dbox) RunCommand(command string, args []string, engineFlags []string, u
 		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	}
 
+	var output io.Writer = os.Stdout
+	var errOutput io.Writer = os.Stderr
+	if d.output != nil {
+		output, errOutput = d.output, d.output
+	}
+
 	if !captureOutput && !muteOutput {
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = output
 	}
 	if !muteOutput {
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = errOutput
+	}
+	// a captured command can't show its prompts, so it must not wait for
+	// an answer on stdin
+	if !captureOutput {
+		cmd.Stdin = os.Stdin
 	}
-	cmd.Stdin = os.Stdin
 
 	if !settings.IsFlatpak() {
 		cmd.Env = append(os.Environ(), envVars...)
@@ -187,7 +240,7 @@ func (d *dbox) RunCommand(command string, args []string, engineFlags []string, u
 		output, err := cmd.Output()
 		if err != nil {
 			if exitErr, ok := err.(*exec.ExitError); ok {
-				return output, errors.New(string(exitErr.Stderr))
+				return output, &ExecError{Stdout: string(output), Stderr: string(exitErr.Stderr), Err: exitErr}
 			}
 		}
 		return output, err
@@ -266,7 +319,171 @@ func (d *dbox) GetContainer(name string, rootFull bool) (*dboxContainer, error)
 		}
 	}
 
-	return nil, errors.New("container not found")
+	return nil, &NotFoundError{Err: ErrContainerNotFound, Name: name}
+}
+
+// ContainerInspect returns the details of the container, including its
+// disk usage.
+func (d *dbox) ContainerInspect(name string, rootFull bool) (*dboxContainerInspect, error) {
+	output, err := d.RunCommand("inspect", []string{
+		"--type", "container",
+		"--size",
+		name,
+	}, []string{}, true, true, true, rootFull, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []dboxContainerInspect
+	err = json.Unmarshal(output, &containers)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, &NotFoundError{Err: ErrContainerNotFound, Name: name}
+	}
+
+	container := containers[0]
+	if container.ImageName == "" {
+		container.ImageName = container.Config.Image
+	}
+
+	return &container, nil
+}
+
+// ImageInspect returns the details of the image.
+func (d *dbox) ImageInspect(image string, rootFull bool) (*dboxImageInspect, error) {
+	images, err := d.imagesInspect([]string{image}, rootFull)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, &NotFoundError{Err: ErrImageNotFound, Name: image}
+	}
+
+	return &images[0], nil
+}
+
+func (d *dbox) imagesInspect(images []string, rootFull bool) ([]dboxImageInspect, error) {
+	output, err := d.RunCommand("image", append([]string{"inspect"}, images...), []string{}, true, true, true, rootFull, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var inspected []dboxImageInspect
+	err = json.Unmarshal(output, &inspected)
+	if err != nil {
+		return nil, err
+	}
+
+	return inspected, nil
+}
+
+// PullImage makes the image available locally, pulling it according to
+// the pull policy.
+func (d *dbox) PullImage(image string, pullPolicy string, rootFull bool) error {
+	// snapshots only exist locally, pulling them would fail
+	if isSnapshotImage(image) {
+		pullPolicy = PullPolicyNever
+	}
+
+	switch pullPolicy {
+	case PullPolicyMissing:
+		if d.ImageExists(image, rootFull) {
+			return nil
+		}
+	case PullPolicyNever:
+		if !d.ImageExists(image, rootFull) {
+			return fmt.Errorf("%w: %s is not available locally and the pull policy is %s", ErrImageNotFound, image, PullPolicyNever)
+		}
+		return nil
+	}
+
+	_, err := d.RunCommand("pull", []string{
+		image,
+	}, []string{}, true, false, false, rootFull, false)
+	return err
+}
+
+// ImageExists reports whether the image is available locally.
+func (d *dbox) ImageExists(image string, rootFull bool) bool {
+	_, err := d.ImageInspect(image, rootFull)
+	return err == nil
+}
+
+// ImageLoad loads the images stored in the archive, returning their names.
+func (d *dbox) ImageLoad(path string, rootFull bool) ([]string, error) {
+	output, err := d.RunCommand("load", []string{
+		"--input", path,
+	}, []string{}, true, true, true, rootFull, false)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		_, names, ok := strings.Cut(line, "Loaded image")
+		if !ok {
+			continue
+		}
+
+		// podman reports "Loaded image(s): a,b", docker "Loaded image: a"
+		_, names, _ = strings.Cut(names, ": ")
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				images = append(images, name)
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// ImageSave stores the image in an archive at path.
+func (d *dbox) ImageSave(image string, path string, rootFull bool) error {
+	_, err := d.RunCommand("save", []string{
+		"--output", path,
+		image,
+	}, []string{}, true, false, true, rootFull, false)
+	return err
+}
+
+// ListImages returns the details of all the images, including the
+// dangling ones.
+func (d *dbox) ListImages(rootFull bool) ([]dboxImageInspect, error) {
+	output, err := d.RunCommand("images", []string{
+		"--quiet",
+		"--no-trunc",
+	}, []string{}, true, true, true, rootFull, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, id := range strings.Fields(string(output)) {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []dboxImageInspect{}, nil
+	}
+
+	return d.imagesInspect(ids, rootFull)
+}
+
+// ImageRemove deletes the image.
+func (d *dbox) ImageRemove(image string, rootFull bool) error {
+	_, err := d.RunCommand("rmi", []string{
+		image,
+	}, []string{}, true, false, true, rootFull, false)
+	return err
+}
+
+// imageID returns the image ID without the digest algorithm, which is
+// only reported by docker.
+func imageID(id string) string {
+	return strings.TrimPrefix(id, "sha256:")
 }
 
 func (d *dbox) ContainerDelete(name string, rootFull bool) error {
@@ -277,13 +494,12 @@ func (d *dbox) ContainerDelete(name string, rootFull bool) error {
 	return err
 }
 
-func (d *dbox) CreateContainer(name string, image string, additionalPackages []string, home string, labels map[string]string, withInit bool, rootFull bool, unshared bool, withNvidiaIntegration bool, hostname string, additionalArgs ...string) error {
+func (d *dbox) CreateContainer(name string, image string, additionalPackages []string, home string, labels map[string]string, extraEngineFlags []string, withInit bool, rootFull bool, unshared bool, withNvidiaIntegration bool, hostname string, additionalArgs ...string) error {
 	args := []string{
 		"--image", image,
 		"--name", name,
 		"--no-entry",
 		"--yes",
-		"--pull",
 	}
 
 	if home != "" {
@@ -318,6 +534,7 @@ func (d *dbox) CreateContainer(name string, image string, additionalPackages []s
 		engineFlags = append(engineFlags, fmt.Sprintf("--label=%s=%s", key, value))
 	}
 	engineFlags = append(engineFlags, "--label=manager=apx")
+	engineFlags = append(engineFlags, extraEngineFlags...)
 
 	_, err := d.RunCommand("create", args, engineFlags, false, false, false, rootFull, false)
 	// fmt.Println(string(out))
@@ -336,7 +553,7 @@ func (d *dbox) RunContainerCommand(name string, command []string, rootFull, deta
 	return err
 }
 
-func (d *dbox) ContainerExec(name string, captureOutput bool, muteOutput bool, rootFull, detachedMode bool, args ...string) (string, error) {
+func (d *dbox) ContainerExec(name string, captureOutput bool, muteOutput bool, rootFull, detachedMode bool, env []string, args ...string) (string, error) {
 	finalArgs := []string{
 		// "--verbose",
 		name,
@@ -344,7 +561,10 @@ func (d *dbox) ContainerExec(name string, captureOutput bool, muteOutput bool, r
 	}
 
 	finalArgs = append(finalArgs, args...)
-	engineFlags := []string{}
+	engineFlags, err := envEngineFlags(name, env)
+	if err != nil {
+		return "", err
+	}
 
 	out, err := d.RunCommand("enter", finalArgs, engineFlags, false, captureOutput, muteOutput, rootFull, detachedMode)
 	// if error 130, it means that the user pressed CTRL+D, so ignore
@@ -355,14 +575,17 @@ func (d *dbox) ContainerExec(name string, captureOutput bool, muteOutput bool, r
 	return string(out), err
 }
 
-func (d *dbox) ContainerEnter(name string, rootFull bool) error {
+func (d *dbox) ContainerEnter(name string, rootFull bool, env []string) error {
 	finalArgs := []string{
 		name,
 	}
 
-	engineFlags := []string{}
+	engineFlags, err := envEngineFlags(name, env)
+	if err != nil {
+		return err
+	}
 
-	_, err := d.RunCommand("enter", finalArgs, engineFlags, false, false, false, rootFull, false)
+	_, err = d.RunCommand("enter", finalArgs, engineFlags, false, false, false, rootFull, false)
 	// if error 130, it means that the user pressed CTRL+D, so ignore
 	if err != nil && err.Error() == "exit status 130" {
 		return nil
@@ -399,7 +622,7 @@ func (d *dbox) ContainerExport(name string, delete bool, rootFull bool, args ...
 
 	finalArgs = append(finalArgs, args...)
 
-	_, err := d.ContainerExec(name, true, true, rootFull, false, finalArgs...)
+	_, err := d.ContainerExec(name, true, true, rootFull, false, nil, finalArgs...)
 	return err
 }
 
@@ -422,3 +645,53 @@ func (d *dbox) ContainerUnexportBin(containerName string, binary string, rootFul
 	args := []string{"--bin", binary}
 	return d.ContainerExport(containerName, true, rootFull, args...)
 }
+
+// envEngineFlags returns the engine flags setting the environment
+// variables of the container, given in the KEY=VALUE format. They are
+// written to the env file of the container, since distrobox splits the
+// additional flags on whitespace.
+func envEngineFlags(name string, env []string) ([]string, error) {
+	if len(env) == 0 {
+		return []string{}, nil
+	}
+
+	path, err := writeEnvFile(name, env)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{"--env-file", path}, nil
+}
+
+// ContainerCommit saves the current state of the container as an image.
+func (d *dbox) ContainerCommit(name string, image string, rootFull bool) error {
+	_, err := d.RunCommand("commit", []string{
+		name,
+		image,
+	}, []string{}, true, false, true, rootFull, false)
+	return err
+}
+
+// snapshotImageName returns the name of the image holding the snapshot of
+// a container, used as the base image of cloned subsystems.
+func snapshotImageName(internalName string) string {
+	return fmt.Sprintf("%s%s", snapshotImagePrefix, internalName)
+}
+
+func isSnapshotImage(image string) bool {
+	return strings.HasPrefix(image, snapshotImagePrefix) || strings.HasPrefix(image, "localhost/"+snapshotImagePrefix)
+}
+
+// Name returns the name of the container.
+func (c dboxContainer) Name() string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// ContainerUpdate changes the resource limits of an existing container.
+func (d *dbox) ContainerUpdate(name string, flags []string, rootFull bool) error {
+	_, err := d.RunCommand("update", append(flags, name), []string{}, true, false, true, rootFull, false)
+	return err
+}
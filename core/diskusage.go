@@ -0,0 +1,267 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SubSystemDiskUsage represents the space used by a subsystem. The home
+// size is only computed for subsystems with a custom home directory.
+type SubSystemDiskUsage struct {
+	Name          string
+	Stack         string
+	RootFull      bool
+	ContainerSize int64
+	Home          string `json:",omitempty"`
+	HomeSize      int64
+}
+
+// ImageDiskUsage represents an image used by apx, either the base image of
+// one or more stacks or a snapshot taken when cloning or recreating a
+// subsystem.
+type ImageDiskUsage struct {
+	ID       string
+	Names    []string
+	Size     int64
+	Stacks   []string `json:",omitempty"`
+	Snapshot bool
+	RootFull bool
+	InUse    bool
+
+	layers []string
+}
+
+// DiskUsage represents the space used by the subsystems and the images
+// managed by apx.
+type DiskUsage struct {
+	Subsystems []SubSystemDiskUsage
+	Images     []ImageDiskUsage
+	Total      int64
+}
+
+// Prunable returns the images which are not used by any container, the
+// ones built on top of others first so that they can be removed in order.
+func (u *DiskUsage) Prunable() []ImageDiskUsage {
+	images := []ImageDiskUsage{}
+	for _, image := range u.Images {
+		if !image.InUse {
+			images = append(images, image)
+		}
+	}
+	slices.SortStableFunc(images, func(a, b ImageDiskUsage) int {
+		return len(b.layers) - len(a.layers)
+	})
+	return images
+}
+
+// GetDiskUsage computes the space used by the subsystems, their homes and
+// the images of the stacks. Images used by any container, even the ones
+// not managed by apx, are marked as in use, as well as the images they are
+// built on. The rootful subsystems and images are only included with
+// includeRootFull, since listing them requires authentication.
+func GetDiskUsage(includeRootFull bool) (*DiskUsage, error) {
+	dbox, err := NewDbox()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &DiskUsage{
+		Subsystems: []SubSystemDiskUsage{},
+		Images:     []ImageDiskUsage{},
+	}
+
+	scopes := []bool{false}
+	if includeRootFull {
+		scopes = append(scopes, true)
+	}
+
+	for _, rootFull := range scopes {
+		err = usage.collect(dbox, rootFull)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortStableFunc(usage.Images, func(a, b ImageDiskUsage) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return usage, nil
+}
+
+// collect adds the subsystems and the images of the rootless or rootful
+// engine to the disk usage.
+func (u *DiskUsage) collect(dbox *dbox, rootFull bool) error {
+	containers, err := dbox.ListContainers(rootFull)
+	if err != nil {
+		return err
+	}
+
+	inspected := map[string]*dboxContainerInspect{}
+	usedImages := map[string]bool{}
+	for _, container := range containers {
+		info, err := dbox.ContainerInspect(container.Name(), rootFull)
+		if err != nil {
+			continue
+		}
+		inspected[container.Name()] = info
+		usedImages[imageID(info.Image)] = true
+	}
+
+	subSystems, err := ListSubSystems(false, rootFull)
+	if err != nil {
+		return err
+	}
+
+	for _, subSystem := range subSystems {
+		subSystemUsage := SubSystemDiskUsage{
+			Name:     subSystem.Name,
+			Stack:    subSystem.Stack.Name,
+			RootFull: rootFull,
+			Home:     subSystem.Home,
+		}
+		if info, ok := inspected[subSystem.InternalName]; ok {
+			subSystemUsage.ContainerSize = info.SizeRw
+		}
+		if subSystem.Home != "" {
+			subSystemUsage.HomeSize = dirSize(subSystem.Home)
+		}
+
+		u.Subsystems = append(u.Subsystems, subSystemUsage)
+		u.Total += subSystemUsage.ContainerSize + subSystemUsage.HomeSize
+	}
+
+	allImages, err := dbox.ListImages(rootFull)
+	if err != nil {
+		return err
+	}
+
+	// the engines refuse to remove an image other images are built on, so
+	// the ancestors of the images in use are in use as well
+	usedLayers := [][]string{}
+	for _, image := range allImages {
+		if usedImages[imageID(image.ID)] {
+			usedLayers = append(usedLayers, image.RootFS.Layers)
+		}
+	}
+
+	images := map[string]*ImageDiskUsage{}
+	addImage := func(image dboxImageInspect) *ImageDiskUsage {
+		id := imageID(image.ID)
+		if _, ok := images[id]; !ok {
+			images[id] = &ImageDiskUsage{
+				ID:       id,
+				Names:    image.RepoTags,
+				Size:     image.Size,
+				RootFull: rootFull,
+				InUse:    usedImages[id] || isAncestor(image.RootFS.Layers, usedLayers),
+				layers:   image.RootFS.Layers,
+			}
+		}
+		return images[id]
+	}
+
+	for _, stack := range ListStacks() {
+		image, err := dbox.ImageInspect(stack.Base, rootFull)
+		if err != nil {
+			continue
+		}
+
+		imageUsage := addImage(*image)
+		imageUsage.Stacks = append(imageUsage.Stacks, stack.Name)
+	}
+
+	for _, image := range allImages {
+		if isApxSnapshot(image) {
+			addImage(image).Snapshot = true
+		}
+	}
+
+	for _, image := range images {
+		u.Images = append(u.Images, *image)
+		u.Total += image.Size
+	}
+
+	return nil
+}
+
+// isAncestor reports whether the image with the given layers is the base of
+// an image with one of the other layer lists.
+func isAncestor(layers []string, others [][]string) bool {
+	if len(layers) == 0 {
+		return false
+	}
+
+	for _, other := range others {
+		if len(other) > len(layers) && slices.Equal(other[:len(layers)], layers) {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneImages removes the given images, in order, and returns the ones
+// which were removed. An image which cannot be removed does not stop the
+// others, the failures are returned together.
+func PruneImages(images []ImageDiskUsage) ([]ImageDiskUsage, error) {
+	dbox, err := NewDbox()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []ImageDiskUsage{}
+	errs := []error{}
+	for _, image := range images {
+		err = dbox.ImageRemove(image.ID, image.RootFull)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", image.ID, err))
+			continue
+		}
+		removed = append(removed, image)
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// isApxSnapshot reports whether the image was committed from a subsystem.
+// Older snapshots lose their tag when a new one is taken, but keep the
+// labels of the container they come from.
+func isApxSnapshot(image dboxImageInspect) bool {
+	for _, tag := range image.RepoTags {
+		if isSnapshotImage(tag) {
+			return true
+		}
+	}
+	return image.Config.Labels["manager"] == "apx"
+}
+
+// dirSize returns the size of the files in the directory, skipping the
+// ones which cannot be read.
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
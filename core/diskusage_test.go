@@ -0,0 +1,63 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"testing"
+)
+
+func TestIsAncestor(t *testing.T) {
+	used := [][]string{{"a", "b", "c"}}
+
+	tests := []struct {
+		name   string
+		layers []string
+		want   bool
+	}{
+		{"base", []string{"a"}, true},
+		{"older snapshot", []string{"a", "b"}, true},
+		{"same image", []string{"a", "b", "c"}, false},
+		{"sibling", []string{"a", "d"}, false},
+		{"no layers", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAncestor(tt.layers, used); got != tt.want {
+				t.Errorf("isAncestor(%q) = %v, want %v", tt.layers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrunableOrder(t *testing.T) {
+	usage := &DiskUsage{Images: []ImageDiskUsage{
+		{ID: "base", layers: []string{"a"}},
+		{ID: "used", InUse: true, layers: []string{"a", "b", "c"}},
+		{ID: "snapshot", layers: []string{"a", "d"}},
+		{ID: "newer-snapshot", layers: []string{"a", "d", "e"}},
+	}}
+
+	images := usage.Prunable()
+	ids := []string{}
+	for _, image := range images {
+		ids = append(ids, image.ID)
+	}
+
+	want := []string{"newer-snapshot", "snapshot", "base"}
+	if len(ids) != len(want) {
+		t.Fatalf("Prunable() = %q, want %q", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Prunable() = %q, want %q", ids, want)
+		}
+	}
+}
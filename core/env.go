@@ -0,0 +1,208 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var envKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// exportEnvRegex matches the environment flags previously added to an
+// exported binary, so they can be replaced.
+var exportEnvRegex = regexp.MustCompile(` --additional-flags "[^"]*"`)
+
+// ValidateEnv checks the environment variable can be written in the env
+// file of the subsystem, which holds a variable per line. Values are set as
+// they are, so they cannot reference other variables.
+func ValidateEnv(key string, value string) error {
+	if !envKeyRegex.MatchString(key) {
+		return &InvalidArgumentError{Argument: "environment variable name", Value: key}
+	}
+
+	if strings.ContainsAny(value, "\n\r\x00") {
+		return &InvalidArgumentError{Argument: "value of " + key, Reason: "it cannot contain line breaks"}
+	}
+
+	return nil
+}
+
+// envFilePath returns the path of the env file passed to the engine when
+// entering the container.
+func envFilePath(internalName string) string {
+	return filepath.Join(apx.Cnf.ApxStoragePath, "subsystems", internalName+".env")
+}
+
+// writeEnvFile writes the environment variables, in the KEY=VALUE format,
+// to the env file of the container and returns its path. The file is
+// replaced atomically, since running commands may be reading it.
+func writeEnvFile(internalName string, env []string) (string, error) {
+	path := envFilePath(internalName)
+
+	// the path is passed through the additional flags of distrobox, which
+	// are split on whitespace
+	if strings.ContainsAny(path, " \t\n") {
+		return "", fmt.Errorf("the environment variables cannot be set since the apx storage path %q contains whitespace", apx.Cnf.ApxStoragePath)
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(strings.Join(env, "\n") + "\n")
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.Rename(f.Name(), path)
+}
+
+// AllEnv returns the environment variables of the stack, overridden by the
+// ones of the subsystem.
+func (s *SubSystem) AllEnv() map[string]string {
+	env := map[string]string{}
+	if s.Stack != nil {
+		maps.Copy(env, s.Stack.Env)
+	}
+	maps.Copy(env, s.Env)
+	return env
+}
+
+// envList returns the environment variables of the subsystem in the
+// KEY=VALUE format, sorted by name.
+func (s *SubSystem) envList() []string {
+	env := s.AllEnv()
+	list := make([]string, 0, len(env))
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		list = append(list, key+"="+env[key])
+	}
+	return list
+}
+
+// SetEnv sets the environment variables of the subsystem, updating the
+// exported binaries.
+func (s *SubSystem) SetEnv(env map[string]string) error {
+	for key, value := range env {
+		err := ValidateEnv(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.Env == nil {
+		s.Env = map[string]string{}
+	}
+	maps.Copy(s.Env, env)
+
+	err := s.SaveMetadata()
+	if err != nil {
+		return err
+	}
+
+	return s.updateExportsEnv()
+}
+
+// UnsetEnv removes the environment variables from the subsystem, updating
+// the exported binaries. Variables defined by the stack cannot be removed.
+func (s *SubSystem) UnsetEnv(keys []string) error {
+	for _, key := range keys {
+		if _, ok := s.Env[key]; ok {
+			continue
+		}
+
+		if s.Stack != nil {
+			if _, ok := s.Stack.Env[key]; ok {
+				return &InvalidArgumentError{Argument: "environment variable", Value: key, Reason: fmt.Sprintf("it is defined by the stack %s", s.Stack.Name)}
+			}
+		}
+		return &NotFoundError{Err: ErrEnvNotFound, Name: key}
+	}
+
+	for _, key := range keys {
+		delete(s.Env, key)
+	}
+
+	err := s.SaveMetadata()
+	if err != nil {
+		return err
+	}
+
+	return s.updateExportsEnv()
+}
+
+// updateExportsEnv makes the exported binaries and desktop entries enter
+// the subsystem with its environment variables.
+func (s *SubSystem) updateExportsEnv() error {
+	paths := findExportedBinaryPaths(s.InternalName)
+	paths = append(paths, s.exportedDesktopFiles()...)
+
+	for _, path := range paths {
+		err := s.updateExportEnv(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateExportEnv makes the exported binary or desktop entry at path enter
+// the subsystem with its environment variables.
+func (s *SubSystem) updateExportEnv(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	flags, err := envEngineFlags(s.InternalName, s.envList())
+	if err != nil {
+		return err
+	}
+
+	owner := "-n " + s.InternalName
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, owner+" ") {
+			continue
+		}
+
+		line = exportEnvRegex.ReplaceAllString(line, "")
+		if len(flags) > 0 {
+			line = strings.Replace(line, owner+" ", fmt.Sprintf("%s --additional-flags \"%s\" ", owner, strings.Join(flags, " ")), 1)
+		}
+		lines[i] = line
+	}
+
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
@@ -0,0 +1,98 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateEnv(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{"JAVA_HOME", "/usr/lib/jvm/java-21", false},
+		{"JAVA_TOOL_OPTIONS", `-Xmx1g -Dfoo="bar baz"`, false},
+		{"PATH", "$PATH:/opt/x", false},
+		{"1KEY", "value", true},
+		{"KEY-NAME", "value", true},
+		{"KEY", "first\nsecond", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			err := ValidateEnv(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEnv(%q, %q) error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateExportsEnv(t *testing.T) {
+	home := setupTestApx(t)
+
+	binary := filepath.Join(home, ".local", "bin", "htop")
+	writeTestWrapper(t, binary, "apx-test")
+
+	desktop := filepath.Join(home, ".local", "share", "applications", "apx-test-htop.desktop")
+	err := os.MkdirAll(filepath.Dir(desktop), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(desktop, []byte("[Desktop Entry]\nName=htop on test\nExec=/usr/bin/distrobox-enter -n apx-test -- htop\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &SubSystem{InternalName: "apx-test", Env: map[string]string{"JAVA_TOOL_OPTIONS": "-Xmx1g -Dfoo"}}
+	err = s.updateExportsEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flag := `-n apx-test --additional-flags "--env-file ` + envFilePath("apx-test") + `" `
+	for _, path := range []string{binary, desktop} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Count(string(data), flag) != 1 {
+			t.Errorf("%s does not pass the env file once:\n%s", path, data)
+		}
+	}
+
+	data, err := os.ReadFile(envFilePath("apx-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "JAVA_TOOL_OPTIONS=-Xmx1g -Dfoo\n" {
+		t.Errorf("env file = %q", data)
+	}
+
+	// unsetting every variable drops the flag
+	s.Env = nil
+	err = s.updateExportsEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = os.ReadFile(desktop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "--additional-flags") {
+		t.Errorf("the desktop entry still passes the env file:\n%s", data)
+	}
+}
@@ -0,0 +1,195 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// Errors returned by core, to be checked with errors.Is. Most of them are
+// wrapped in one of the typed errors below, carrying the details.
+var (
+	ErrSubsystemNotFound    = errors.New("subsystem not found")
+	ErrSubsystemExists      = errors.New("subsystem already exists")
+	ErrInvalidSubsystemName = errors.New("invalid subsystem name")
+	ErrContainerNotFound    = errors.New("container not found")
+	ErrImageNotFound        = errors.New("image not found")
+	ErrCommandNotFound      = errors.New("command not found")
+	ErrStackNotFound        = errors.New("stack not found")
+	ErrStackInvalid         = errors.New("invalid stack file")
+	ErrStackBuiltIn         = errors.New("cannot remove built-in stack")
+	ErrPkgManagerNotFound   = errors.New("package manager not found")
+	ErrPkgManagerBuiltIn    = errors.New("cannot remove built-in package manager")
+	ErrUnsupportedOperation = errors.New("operation not supported by the package manager")
+	ErrVolumeNotFound       = errors.New("volume not found")
+	ErrEnvNotFound          = errors.New("environment variable not set")
+	ErrInvalidArgument      = errors.New("invalid argument")
+	ErrDistroboxNotFound    = errors.New("distrobox is not installed")
+	ErrEngineNotFound       = errors.New("container engine (docker or podman) not found")
+)
+
+// NotFoundError is returned when a subsystem, stack, package manager,
+// container, image, volume or environment variable does not exist. Err is the sentinel telling which
+// one, e.g. errors.Is(err, ErrStackNotFound).
+type NotFoundError struct {
+	Err  error
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, e.Name)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// ExistsError is returned when creating a subsystem whose name is already
+// taken. It matches ErrSubsystemExists.
+type ExistsError struct {
+	Name string
+}
+
+func (e *ExistsError) Error() string {
+	return fmt.Sprintf("subsystem %s already exists", e.Name)
+}
+
+func (e *ExistsError) Is(target error) bool {
+	return target == ErrSubsystemExists
+}
+
+// InvalidNameError is returned by ValidateSubSystemName, Reason tells why
+// the name was rejected. It matches ErrInvalidSubsystemName.
+type InvalidNameError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidNameError) Error() string {
+	return fmt.Sprintf("invalid subsystem name %q: %s", e.Name, e.Reason)
+}
+
+func (e *InvalidNameError) Is(target error) bool {
+	return target == ErrInvalidSubsystemName
+}
+
+// InvalidArgumentError is returned when a value passed to core, e.g. a
+// resource limit, a volume or an environment variable, cannot be used.
+// Value is left empty when it should not be printed, Reason when the name
+// of the argument says enough. It matches ErrInvalidArgument.
+type InvalidArgumentError struct {
+	Argument string
+	Value    string
+	Reason   string
+}
+
+func (e *InvalidArgumentError) Error() string {
+	msg := "invalid " + e.Argument
+	if e.Value != "" {
+		msg += fmt.Sprintf(" %q", e.Value)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *InvalidArgumentError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// InvalidStackError is returned when a stack file cannot be parsed or
+// lacks a required field. Field is the missing field, empty when Err holds
+// the parsing error. It matches ErrStackInvalid.
+type InvalidStackError struct {
+	Path  string
+	Field string
+	Err   error
+}
+
+func (e *InvalidStackError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("invalid stack file %s: missing %s", e.Path, e.Field)
+	}
+	return fmt.Sprintf("invalid stack file %s: %v", e.Path, e.Err)
+}
+
+func (e *InvalidStackError) Is(target error) bool {
+	return target == ErrStackInvalid
+}
+
+func (e *InvalidStackError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedOperationError is returned when the package manager of a
+// subsystem has no command for the requested operation. It matches
+// ErrUnsupportedOperation.
+type UnsupportedOperationError struct {
+	PkgManager string
+	Operation  string
+}
+
+func (e *UnsupportedOperationError) Error() string {
+	return fmt.Sprintf("package manager %s does not support %s", e.PkgManager, e.Operation)
+}
+
+func (e *UnsupportedOperationError) Is(target error) bool {
+	return target == ErrUnsupportedOperation
+}
+
+// ExecError is returned when a command run with its output captured fails.
+// Its message is what the command printed on stderr, while the exit status
+// is available through the wrapped exec.ExitError. Stdout keeps the output
+// captured before the failure, so that it is not lost.
+type ExecError struct {
+	Stdout string
+	Stderr string
+	Err    *exec.ExitError
+}
+
+func (e *ExecError) Error() string {
+	if e.Stderr == "" {
+		return e.Err.Error()
+	}
+	return e.Stderr
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// Steps of Recreate, reported by RecreateError.
+const (
+	RecreateStepSnapshot = "snapshot"
+	RecreateStepCreate   = "create"
+	RecreateStepExports  = "exports"
+	RecreateStepRemove   = "remove"
+)
+
+// RecreateError is returned by Recreate when one of its steps fails. Up to
+// RecreateStepExports the subsystem is left as it was, while a failure
+// at RecreateStepRemove leaves the previous container next to the new one.
+// Snapshot is the image the new container is created from.
+type RecreateError struct {
+	Step     string
+	Snapshot string
+	Err      error
+}
+
+func (e *RecreateError) Error() string {
+	return fmt.Sprintf("recreating the subsystem failed at the %s step: %v", e.Step, e.Err)
+}
+
+func (e *RecreateError) Unwrap() error {
+	return e.Err
+}
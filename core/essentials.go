@@ -10,7 +10,6 @@ package core
 */
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -20,47 +19,39 @@ import (
 	"github.com/vanilla-os/apx/v3/settings"
 )
 
+// EssentialChecks makes sure the container tools are available and creates
+// the directories used by apx when missing.
 func (a *Apx) EssentialChecks() error {
 	err := a.CheckContainerTools()
 	if err != nil {
-		fmt.Println(`One or more core components are not available. 
-Please refer to our documentation at https://documentation.vanillaos.org/`)
 		return err
 	}
 
 	err = a.CheckAndCreateUserStacksDirectory()
 	if err != nil {
-		fmt.Println(`Failed to create stacks directory.`)
 		return err
 	}
 
 	err = a.CheckAndCreateApxStorageDirectory()
 	if err != nil {
-		fmt.Println(`Failed to create apx storage directory.`)
 		return err
 	}
 
-	err = a.CheckAndCreateApxUserPkgManagersDirectory()
-	if err != nil {
-		fmt.Println(`Failed to create apx user pkg managers directory.`)
-		return err
-	}
-
-	return nil
+	return a.CheckAndCreateApxUserPkgManagersDirectory()
 }
 
 func (a *Apx) CheckContainerTools() error {
 	err := settings.TestFile(a.Cnf.DistroboxPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return errors.New("distrobox is not installed")
+			return ErrDistroboxNotFound
 		}
 		return err
 	}
 
 	if _, err := settings.LookPath("docker"); err != nil {
 		if _, err := settings.LookPath("podman"); err != nil {
-			return errors.New("container engine (docker or podman) not found")
+			return ErrEngineNotFound
 		}
 	}
 
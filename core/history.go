@@ -0,0 +1,86 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HistoryEntry represents a state-changing command run by apx, as stored
+// in the history log.
+type HistoryEntry struct {
+	Time       time.Time
+	Subsystem  string `json:",omitempty"`
+	Stack      string `json:",omitempty"`
+	PkgManager string `json:",omitempty"`
+	Command    string
+	Duration   time.Duration
+	ExitStatus int
+	Error      string `json:",omitempty"`
+}
+
+func historyLogPath() string {
+	return filepath.Join(apx.Cnf.ApxStoragePath, "history.jsonl")
+}
+
+// LogHistory appends the entry to the history log.
+func LogHistory(entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(historyLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ListHistory returns the entries stored in the history log, oldest first.
+// If subSystem is not empty, only the entries of that subsystem are
+// returned. Malformed lines are skipped.
+func ListHistory(subSystem string) ([]HistoryEntry, error) {
+	entries := []HistoryEntry{}
+
+	f, err := os.Open(historyLogPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+
+		if subSystem != "" && entry.Subsystem != subSystem {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
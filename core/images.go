@@ -0,0 +1,91 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/vanilla-os/apx/v3/settings"
+)
+
+const (
+	// PullPolicyAlways pulls the base image every time a subsystem is
+	// created, the default.
+	PullPolicyAlways = "always"
+
+	// PullPolicyMissing only pulls the base image if it is not available
+	// locally.
+	PullPolicyMissing = "missing"
+
+	// PullPolicyNever never pulls the base image, creating a subsystem
+	// fails if it is not available locally.
+	PullPolicyNever = "never"
+)
+
+// ValidatePullPolicy checks the pull policy is one of the supported ones.
+// An empty policy means the default one. Invalid policies match
+// settings.ErrInvalidPullPolicy.
+func ValidatePullPolicy(policy string) error {
+	return settings.ValidatePullPolicy(policy)
+}
+
+// GetPullPolicy returns the pull policy of the stack, falling back to the
+// global one and then to PullPolicyAlways. Both are validated when loaded.
+func (stack *Stack) GetPullPolicy() string {
+	for _, policy := range []string{stack.PullPolicy, apx.Cnf.PullPolicy} {
+		if policy != "" {
+			return policy
+		}
+	}
+	return PullPolicyAlways
+}
+
+// LoadImages loads the images stored in the archive at path, so that they
+// can be used as stack base images without network access. The names of
+// the loaded images are returned.
+func LoadImages(path string) ([]string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	dbox, err := NewDbox()
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return dbox.ImageLoad(absPath, false)
+}
+
+// SaveImage stores the base image of the stack in an archive at path,
+// pulling it first if it is not available locally.
+func (stack *Stack) SaveImage(path string) error {
+	dbox, err := NewDbox()
+	if err != nil {
+		return err
+	}
+
+	err = dbox.PullImage(stack.Base, PullPolicyMissing, false)
+	if err != nil {
+		return err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	return dbox.ImageSave(stack.Base, absPath, false)
+}
@@ -0,0 +1,129 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// SubSystemInfo represents the full state of a subsystem, as shown by
+// "apx subsystems inspect". Fields which cannot be determined are left
+// empty, e.g. the installed packages of a stopped subsystem.
+type SubSystemInfo struct {
+	Name              string
+	InternalName      string
+	ID                string
+	Stack             string
+	PkgManager        string
+	Packages          []string
+	Base              string
+	Image             string
+	ImageDigest       string `json:",omitempty"`
+	Status            string
+	Running           bool
+	IsRootfull        bool
+	IsUnshared        bool
+	HasInit           bool
+	HasNvidia         bool
+	Home              string
+	Hostname          string
+	Created           time.Time
+	Started           *time.Time `json:",omitempty"`
+	ContainerSize     int64
+	ImageSize         int64
+	InstalledPackages *int `json:",omitempty"`
+	ExportedApps      []string
+	ExportedBinaries  []string
+	LastUpgrade       *time.Time `json:",omitempty"`
+	Limits            ResourceLimits
+	Volumes           []Volume
+	Env               map[string]string
+}
+
+// Uptime returns for how long the subsystem has been running, or zero if
+// it is stopped.
+func (i *SubSystemInfo) Uptime() time.Duration {
+	if !i.Running || i.Started == nil {
+		return 0
+	}
+	return time.Since(*i.Started)
+}
+
+// Inspect collects the state of the subsystem from its container, its
+// image and the apx stores. The installed packages are only counted when
+// the subsystem is running, to avoid starting it.
+func (s *SubSystem) Inspect() (*SubSystemInfo, error) {
+	dbox, err := NewDbox()
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := dbox.ContainerInspect(s.InternalName, s.IsRootfull)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SubSystemInfo{
+		Name:             s.Name,
+		InternalName:     s.InternalName,
+		ID:               s.ID,
+		Stack:            s.Stack.Name,
+		PkgManager:       s.Stack.PkgManager,
+		Packages:         s.Stack.Packages,
+		Base:             s.Stack.Base,
+		Image:            container.ImageName,
+		Status:           s.Status,
+		Running:          container.State.Running,
+		IsRootfull:       s.IsRootfull,
+		IsUnshared:       s.IsUnshared,
+		HasInit:          s.HasInit,
+		HasNvidia:        s.HasNvidiaIntegration,
+		Home:             s.Home,
+		Hostname:         s.Hostname,
+		Created:          container.Created,
+		ContainerSize:    container.SizeRw,
+		ExportedApps:     s.exportedApps(),
+		ExportedBinaries: slices.Sorted(maps.Keys(findExportedBinaries(s.InternalName))),
+		LastUpgrade:      s.LastUpgrade,
+		Limits:           s.Limits,
+		Volumes:          s.AllVolumes(),
+		Env:              s.AllEnv(),
+	}
+
+	if container.State.Running {
+		info.Started = &container.State.StartedAt
+
+		packages, err := s.InstalledPackages()
+		if err == nil {
+			count := len(packages)
+			info.InstalledPackages = &count
+		}
+	}
+
+	image, err := dbox.ImageInspect(container.Image, s.IsRootfull)
+	if err == nil {
+		info.ImageSize = image.Size
+		if len(image.RepoDigests) > 0 {
+			info.ImageDigest = image.RepoDigests[0]
+		}
+	}
+
+	return info, nil
+}
+
+// MarkUpgraded records that the packages of the subsystem have just been
+// upgraded.
+func (s *SubSystem) MarkUpgraded() error {
+	now := time.Now()
+	s.LastUpgrade = &now
+	return s.SaveMetadata()
+}
@@ -0,0 +1,137 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ResourceLimits represents the resources a subsystem container can use.
+// Zero values mean no limit. CPUs is a number of CPUs, possibly
+// fractional, Memory a size like "512m" or "4g", IOWeight a relative
+// weight between 10 and 1000.
+type ResourceLimits struct {
+	CPUs     string `json:",omitempty"`
+	Memory   string `json:",omitempty"`
+	PIDs     int    `json:",omitempty"`
+	IOWeight int    `json:",omitempty"`
+}
+
+var memoryLimitRegex = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// Validate checks the limits can be passed to the container engine.
+func (l ResourceLimits) Validate() error {
+	if l.CPUs != "" {
+		cpus, err := strconv.ParseFloat(l.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			return &InvalidArgumentError{Argument: "CPUs limit", Value: l.CPUs}
+		}
+	}
+
+	if l.Memory != "" && !memoryLimitRegex.MatchString(l.Memory) {
+		return &InvalidArgumentError{Argument: "memory limit", Value: l.Memory}
+	}
+
+	if l.PIDs < 0 {
+		return &InvalidArgumentError{Argument: "PIDs limit", Value: strconv.Itoa(l.PIDs)}
+	}
+
+	if l.IOWeight != 0 && (l.IOWeight < 10 || l.IOWeight > 1000) {
+		return &InvalidArgumentError{Argument: "IO weight", Value: strconv.Itoa(l.IOWeight), Reason: "it must be between 10 and 1000"}
+	}
+
+	return nil
+}
+
+// IsSet reports whether any limit is set.
+func (l ResourceLimits) IsSet() bool {
+	return l != ResourceLimits{}
+}
+
+// EngineFlags returns the container engine flags applying the limits.
+func (l ResourceLimits) EngineFlags() []string {
+	flags := []string{}
+
+	if l.CPUs != "" {
+		flags = append(flags, "--cpus="+l.CPUs)
+	}
+
+	if l.Memory != "" {
+		flags = append(flags, "--memory="+l.Memory)
+	}
+
+	if l.PIDs > 0 {
+		flags = append(flags, fmt.Sprintf("--pids-limit=%d", l.PIDs))
+	}
+
+	if l.IOWeight > 0 {
+		flags = append(flags, fmt.Sprintf("--blkio-weight=%d", l.IOWeight))
+	}
+
+	return flags
+}
+
+// Merge returns the limits with the values set in other replacing the
+// current ones.
+func (l ResourceLimits) Merge(other ResourceLimits) ResourceLimits {
+	if other.CPUs != "" {
+		l.CPUs = other.CPUs
+	}
+
+	if other.Memory != "" {
+		l.Memory = other.Memory
+	}
+
+	if other.PIDs != 0 {
+		l.PIDs = other.PIDs
+	}
+
+	if other.IOWeight != 0 {
+		l.IOWeight = other.IOWeight
+	}
+
+	return l
+}
+
+// SetLimits changes the resource limits of the subsystem. The running
+// container is updated in place when the engine supports it, otherwise it
+// is recreated from a snapshot of its current state.
+func (s *SubSystem) SetLimits(limits ResourceLimits) error {
+	err := limits.Validate()
+	if err != nil {
+		return err
+	}
+
+	dbox, err := NewDbox()
+	if err != nil {
+		return err
+	}
+
+	// removed limits cannot be reset through an update, since the engines
+	// only accept new values
+	removesLimits := s.Limits.Merge(limits) != limits
+	s.Limits = limits
+
+	updated := false
+	if !removesLimits && limits.IsSet() {
+		updated = dbox.ContainerUpdate(s.InternalName, limits.EngineFlags(), s.IsRootfull) == nil
+	}
+
+	if !updated {
+		err = s.Recreate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.SaveMetadata()
+}
@@ -0,0 +1,105 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SubSystemMetadata holds the settings of a subsystem which can change
+// after its creation, so they cannot be stored in the container labels.
+type SubSystemMetadata struct {
+	Limits      ResourceLimits
+	Volumes     []Volume
+	Env         map[string]string
+	LastUpgrade *time.Time `json:",omitempty"`
+	BinPaths    []string   `json:",omitempty"`
+}
+
+func subSystemMetadataPath(internalName string) string {
+	return filepath.Join(apx.Cnf.ApxStoragePath, "subsystems", internalName+".json")
+}
+
+// loadSubSystemMetadata loads the metadata of the subsystem container,
+// returning empty metadata if none was saved.
+func loadSubSystemMetadata(internalName string) (*SubSystemMetadata, error) {
+	metadata := &SubSystemMetadata{}
+
+	data, err := os.ReadFile(subSystemMetadataPath(internalName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return metadata, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// applyMetadata loads the metadata of the subsystem in its fields. Broken
+// metadata files are ignored, so that the subsystem stays usable.
+func (s *SubSystem) applyMetadata() {
+	metadata, err := loadSubSystemMetadata(s.InternalName)
+	if err != nil {
+		return
+	}
+
+	s.Limits = metadata.Limits
+	s.Volumes = metadata.Volumes
+	s.Env = metadata.Env
+	s.LastUpgrade = metadata.LastUpgrade
+	s.BinPaths = metadata.BinPaths
+}
+
+// SaveMetadata stores the settings of the subsystem which are not part of
+// the container labels.
+func (s *SubSystem) SaveMetadata() error {
+	metadata := SubSystemMetadata{
+		Limits:      s.Limits,
+		Volumes:     s.Volumes,
+		Env:         s.Env,
+		LastUpgrade: s.LastUpgrade,
+		BinPaths:    s.BinPaths,
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := subSystemMetadataPath(s.InternalName)
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// RemoveMetadata deletes the metadata and the env file of the subsystem,
+// once it has been removed for good.
+func (s *SubSystem) RemoveMetadata() error {
+	for _, path := range []string{subSystemMetadataPath(s.InternalName), envFilePath(s.InternalName)} {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -42,12 +42,158 @@ type PkgManager struct {
 	CmdUpdate     string
 	CmdUpgrade    string
 
+	// CmdInstallLocal is used to install package files copied from the
+	// host, e.g. "apt install" or "pacman -U". It is optional, leaving it
+	// empty disables local installs.
+	CmdInstallLocal string
+
+	// Extended operations, optional as not every package manager supports
+	// them natively.
+	CmdHold           string
+	CmdUnhold         string
+	CmdListUpgradable string
+	CmdRepoAdd        string
+	CmdRepoRemove     string
+	CmdOwns           string
+
+	// NonInteractiveFlag and NonInteractiveEnv are injected by GenCmd in
+	// the commands changing the subsystem when apx runs in non-interactive
+	// mode (--yes or APX_ASSUME_YES), e.g. "-y" or "--noconfirm" and
+	// "DEBIAN_FRONTEND=noninteractive". NonInteractiveEnv is a space
+	// separated list of KEY=VALUE pairs. The flag is appended to the
+	// command, unless NonInteractiveGlobal is set: it is then placed right
+	// after the executable, as for "zypper --non-interactive install".
+	NonInteractiveFlag   string
+	NonInteractiveEnv    string
+	NonInteractiveGlobal bool
+
 	// BuiltIn:
 	// If true, the package manager is built-in (stored in
 	// /usr/share/apx/pkg-managers) and cannot be removed by the user
 	BuiltIn bool
 }
 
+// Package manager operations, each one is supported when the package
+// manager declares the matching command.
+const (
+	PkgManagerOpAutoRemove     = "autoremove"
+	PkgManagerOpClean          = "clean"
+	PkgManagerOpInstall        = "install"
+	PkgManagerOpInstallLocal   = "install-local"
+	PkgManagerOpList           = "list"
+	PkgManagerOpPurge          = "purge"
+	PkgManagerOpRemove         = "remove"
+	PkgManagerOpSearch         = "search"
+	PkgManagerOpShow           = "show"
+	PkgManagerOpUpdate         = "update"
+	PkgManagerOpUpgrade        = "upgrade"
+	PkgManagerOpHold           = "hold"
+	PkgManagerOpUnhold         = "unhold"
+	PkgManagerOpListUpgradable = "list-upgradable"
+	PkgManagerOpRepoAdd        = "repo-add"
+	PkgManagerOpRepoRemove     = "repo-remove"
+	PkgManagerOpOwns           = "owns"
+)
+
+// PkgManagerOps lists all the known package manager operations.
+var PkgManagerOps = []string{
+	PkgManagerOpInstall,
+	PkgManagerOpInstallLocal,
+	PkgManagerOpRemove,
+	PkgManagerOpPurge,
+	PkgManagerOpAutoRemove,
+	PkgManagerOpClean,
+	PkgManagerOpUpdate,
+	PkgManagerOpUpgrade,
+	PkgManagerOpList,
+	PkgManagerOpListUpgradable,
+	PkgManagerOpSearch,
+	PkgManagerOpShow,
+	PkgManagerOpHold,
+	PkgManagerOpUnhold,
+	PkgManagerOpRepoAdd,
+	PkgManagerOpRepoRemove,
+	PkgManagerOpOwns,
+}
+
+// pkgManagerDefaults holds the settings of the known package managers,
+// keyed by name. The built-in definitions are shipped separately from apx
+// and may predate these settings, so they are used whenever a definition
+// leaves them empty.
+var pkgManagerDefaults = map[string]PkgManager{
+	"apt": {
+		CmdHold:            "apt-mark hold",
+		CmdUnhold:          "apt-mark unhold",
+		CmdListUpgradable:  "apt list --upgradable",
+		CmdRepoAdd:         "add-apt-repository -y",
+		CmdRepoRemove:      "add-apt-repository -y --remove",
+		CmdOwns:            "dpkg -S",
+		NonInteractiveFlag: "-y",
+		NonInteractiveEnv:  "DEBIAN_FRONTEND=noninteractive",
+	},
+	"dnf": {
+		CmdHold:            "dnf versionlock add",
+		CmdUnhold:          "dnf versionlock delete",
+		CmdListUpgradable:  "dnf list --upgrades",
+		CmdRepoAdd:         "dnf config-manager addrepo --from-repofile",
+		CmdOwns:            "rpm -qf",
+		NonInteractiveFlag: "-y",
+	},
+	"pacman": {
+		CmdListUpgradable:  "pacman -Qu",
+		CmdOwns:            "pacman -Qo",
+		NonInteractiveFlag: "--noconfirm",
+	},
+	"apk": {
+		CmdListUpgradable: "apk list --upgradable",
+		CmdOwns:           "apk info --who-owns",
+	},
+	"zypper": {
+		CmdHold:              "zypper addlock",
+		CmdUnhold:            "zypper removelock",
+		CmdListUpgradable:    "zypper list-updates",
+		CmdRepoAdd:           "zypper addrepo",
+		CmdRepoRemove:        "zypper removerepo",
+		CmdOwns:              "rpm -qf",
+		NonInteractiveFlag:   "--non-interactive",
+		NonInteractiveGlobal: true,
+	},
+}
+
+// applyDefaults fills the settings left empty by the definition with the
+// ones of the known package manager of the same name. The commands of model
+// 1 are arguments of the package manager, so they are not completed.
+func (pkgManager *PkgManager) applyDefaults() {
+	defaults, ok := pkgManagerDefaults[pkgManager.Name]
+	if !ok {
+		return
+	}
+
+	for _, cmd := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&pkgManager.CmdHold, defaults.CmdHold},
+		{&pkgManager.CmdUnhold, defaults.CmdUnhold},
+		{&pkgManager.CmdListUpgradable, defaults.CmdListUpgradable},
+		{&pkgManager.CmdRepoAdd, defaults.CmdRepoAdd},
+		{&pkgManager.CmdRepoRemove, defaults.CmdRepoRemove},
+		{&pkgManager.CmdOwns, defaults.CmdOwns},
+	} {
+		if strings.TrimSpace(*cmd.value) == "" && !pkgManager.UsesDeprecatedModel() {
+			*cmd.value = cmd.fallback
+		}
+	}
+
+	// the non-interactive settings go together, a definition setting any
+	// of them is not completed
+	if pkgManager.NonInteractiveFlag == "" && pkgManager.NonInteractiveEnv == "" && !pkgManager.NonInteractiveGlobal {
+		pkgManager.NonInteractiveFlag = defaults.NonInteractiveFlag
+		pkgManager.NonInteractiveEnv = defaults.NonInteractiveEnv
+		pkgManager.NonInteractiveGlobal = defaults.NonInteractiveGlobal
+	}
+}
+
 // NewPkgManager creates a new PkgManager instance.
 func NewPkgManager(name string, needSudo bool, autoRemove, clean, install, list, purge, remove, search, show, update, upgrade string, builtIn bool) *PkgManager {
 	return &PkgManager{
@@ -72,10 +218,13 @@ func NewPkgManager(name string, needSudo bool, autoRemove, clean, install, list,
 func LoadPkgManager(name string) (*PkgManager, error) {
 	userPkgFile := SelectYamlFile(apx.Cnf.UserPkgManagersPath, name)
 	pkgManager, err := loadPkgManagerFromPath(userPkgFile)
-	if err != nil {
+	if errors.Is(err, ErrPkgManagerNotFound) {
 		pkgFile := SelectYamlFile(apx.Cnf.PkgManagersPath, name)
 		pkgManager, err = loadPkgManagerFromPath(pkgFile)
 	}
+	if errors.Is(err, ErrPkgManagerNotFound) {
+		return nil, &NotFoundError{Err: ErrPkgManagerNotFound, Name: name}
+	}
 	return pkgManager, err
 }
 
@@ -84,9 +233,13 @@ func loadPkgManagerFromPath(path string) (*PkgManager, error) {
 	pkgManager := &PkgManager{}
 
 	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, &NotFoundError{Err: ErrPkgManagerNotFound, Name: path}
+	}
 	if err != nil {
-		return nil, errors.New("package manager not found")
+		return nil, err
 	}
+	defer f.Close()
 
 	data, err := io.ReadAll(f)
 	if err != nil {
@@ -98,6 +251,7 @@ func loadPkgManagerFromPath(path string) (*PkgManager, error) {
 		return nil, err
 	}
 
+	pkgManager.applyDefaults()
 	return pkgManager, nil
 }
 
@@ -116,7 +270,7 @@ func (pkgManager *PkgManager) Save() error {
 // Remove removes the package manager from the specified path.
 func (pkgManager *PkgManager) Remove() error {
 	if pkgManager.BuiltIn {
-		return errors.New("cannot remove built-in package manager")
+		return ErrPkgManagerBuiltIn
 	}
 
 	filePath := SelectYamlFile(apx.Cnf.UserPkgManagersPath, pkgManager.Name)
@@ -124,26 +278,140 @@ func (pkgManager *PkgManager) Remove() error {
 	return err
 }
 
+// GetCommand returns the command declared for the specified operation,
+// an empty string means the operation is not supported.
+func (pkgManager *PkgManager) GetCommand(op string) (string, error) {
+	switch op {
+	case PkgManagerOpAutoRemove:
+		return pkgManager.CmdAutoRemove, nil
+	case PkgManagerOpClean:
+		return pkgManager.CmdClean, nil
+	case PkgManagerOpInstall:
+		return pkgManager.CmdInstall, nil
+	case PkgManagerOpInstallLocal:
+		return pkgManager.CmdInstallLocal, nil
+	case PkgManagerOpList:
+		return pkgManager.CmdList, nil
+	case PkgManagerOpPurge:
+		return pkgManager.CmdPurge, nil
+	case PkgManagerOpRemove:
+		return pkgManager.CmdRemove, nil
+	case PkgManagerOpSearch:
+		return pkgManager.CmdSearch, nil
+	case PkgManagerOpShow:
+		return pkgManager.CmdShow, nil
+	case PkgManagerOpUpdate:
+		return pkgManager.CmdUpdate, nil
+	case PkgManagerOpUpgrade:
+		return pkgManager.CmdUpgrade, nil
+	case PkgManagerOpHold:
+		return pkgManager.CmdHold, nil
+	case PkgManagerOpUnhold:
+		return pkgManager.CmdUnhold, nil
+	case PkgManagerOpListUpgradable:
+		return pkgManager.CmdListUpgradable, nil
+	case PkgManagerOpRepoAdd:
+		return pkgManager.CmdRepoAdd, nil
+	case PkgManagerOpRepoRemove:
+		return pkgManager.CmdRepoRemove, nil
+	case PkgManagerOpOwns:
+		return pkgManager.CmdOwns, nil
+	default:
+		return "", fmt.Errorf("unknown package manager operation: %s", op)
+	}
+}
+
+// Supports reports whether the package manager declares a command for the
+// specified operation.
+func (pkgManager *PkgManager) Supports(op string) bool {
+	cmd, err := pkgManager.GetCommand(op)
+	return err == nil && strings.TrimSpace(cmd) != ""
+}
+
+// Capabilities returns the operations supported by the package manager.
+func (pkgManager *PkgManager) Capabilities() []string {
+	capabilities := make([]string, 0)
+	for _, op := range PkgManagerOps {
+		if pkgManager.Supports(op) {
+			capabilities = append(capabilities, op)
+		}
+	}
+	return capabilities
+}
+
+// UsesDeprecatedModel reports whether the package manager still uses model
+// 1, which will be removed in the future.
+func (pkgManager *PkgManager) UsesDeprecatedModel() bool {
+	return pkgManager.Model == 0 || pkgManager.Model == 1
+}
+
+// isMutating reports whether cmd is one of the commands changing the
+// subsystem, the only ones which can prompt the user.
+func (pkgManager *PkgManager) isMutating(cmd string) bool {
+	if strings.TrimSpace(cmd) == "" {
+		return false
+	}
+
+	for _, mutating := range []string{
+		pkgManager.CmdInstall,
+		pkgManager.CmdInstallLocal,
+		pkgManager.CmdRemove,
+		pkgManager.CmdPurge,
+		pkgManager.CmdUpdate,
+		pkgManager.CmdUpgrade,
+		pkgManager.CmdAutoRemove,
+		pkgManager.CmdClean,
+	} {
+		if cmd == mutating {
+			return true
+		}
+	}
+	return false
+}
+
 // GenCmd generates the command to run inside the container.
+// In non-interactive mode, the package manager's NonInteractiveEnv and
+// NonInteractiveFlag are injected in the commands changing the subsystem,
+// query commands such as list or search are left untouched.
 func (pkgManager *PkgManager) GenCmd(cmd string, args ...string) []string {
+	return pkgManager.genCmd(apx != nil && apx.Cnf.AssumeYes, cmd, args...)
+}
+
+func (pkgManager *PkgManager) genCmd(assumeYes bool, cmd string, args ...string) []string {
 	finalArgs := make([]string, 0)
+	nonInteractive := assumeYes && pkgManager.isMutating(cmd)
+	flag := make([]string, 0)
+	if nonInteractive {
+		flag = strings.Fields(pkgManager.NonInteractiveFlag)
+	}
 
 	if pkgManager.NeedSudo {
 		finalArgs = append(finalArgs, "sudo")
 	}
 
-	if pkgManager.Model == 0 || pkgManager.Model == 1 {
-		// no-translate (DEPRECATION WARNING)
-		fmt.Println("!!! DEPRECATION WARNING: Model 1 will be removed in the future, please update your Apx package manager to use model 2.")
-		finalArgs = append(finalArgs, pkgManager.Name)
-		finalArgs = append(finalArgs, cmd)
-		finalArgs = append(finalArgs, args...)
+	// env is used instead of relying on sudo, so that the variables are
+	// also set for package managers which do not need it
+	if nonInteractive && pkgManager.NonInteractiveEnv != "" {
+		finalArgs = append(finalArgs, "env")
+		finalArgs = append(finalArgs, strings.Fields(pkgManager.NonInteractiveEnv)...)
+	}
+
+	cmdItems := strings.Fields(cmd)
+	if pkgManager.UsesDeprecatedModel() {
+		cmdItems = []string{pkgManager.Name, cmd}
+	}
+
+	if pkgManager.NonInteractiveGlobal && len(cmdItems) > 0 {
+		finalArgs = append(finalArgs, cmdItems[0])
+		finalArgs = append(finalArgs, flag...)
+		finalArgs = append(finalArgs, cmdItems[1:]...)
 	} else {
-		cmdItems := strings.Fields(cmd)
 		finalArgs = append(finalArgs, cmdItems...)
-		finalArgs = append(finalArgs, args...)
+		finalArgs = append(finalArgs, flag...)
 	}
 
+	finalArgs = append(finalArgs, args...)
+
 	return finalArgs
 }
 
@@ -201,9 +469,13 @@ func LoadPkgManagerFromPath(path string) (*PkgManager, error) {
 	pkgManager := &PkgManager{}
 
 	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, &NotFoundError{Err: ErrPkgManagerNotFound, Name: path}
+	}
 	if err != nil {
-		return nil, errors.New("package manager not found")
+		return nil, err
 	}
+	defer f.Close()
 
 	data, err := io.ReadAll(f)
 	if err != nil {
@@ -219,6 +491,7 @@ func LoadPkgManagerFromPath(path string) (*PkgManager, error) {
 		pkgManager.Model = 1 // assuming old model if not specified
 	}
 
+	pkgManager.applyDefaults()
 	return pkgManager, nil
 }
 
@@ -244,3 +517,312 @@ func (pkgManager *PkgManager) Export(path string) error {
 
 	return nil
 }
+
+// UpgradablePackage represents a package which can be upgraded, Current
+// is empty when the package manager does not report it.
+type UpgradablePackage struct {
+	Name      string
+	Current   string
+	Candidate string
+}
+
+// ParseUpgradable parses the output of a package manager CmdListUpgradable.
+// The formats of apt, dnf, pacman, apk and zypper are supported, lines which
+// cannot be recognised (headers, notices) are skipped.
+func ParseUpgradable(output string) []UpgradablePackage {
+	packages := make([]UpgradablePackage, 0)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// zypper: v | repository | name | current | available | arch
+		if strings.Contains(line, "|") {
+			cols := strings.Split(line, "|")
+			if len(cols) >= 5 && strings.TrimSpace(cols[0]) == "v" {
+				packages = append(packages, UpgradablePackage{
+					Name:      strings.TrimSpace(cols[2]),
+					Current:   strings.TrimSpace(cols[3]),
+					Candidate: strings.TrimSpace(cols[4]),
+				})
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		// pacman: name current -> candidate
+		if len(fields) == 4 && fields[2] == "->" {
+			packages = append(packages, UpgradablePackage{
+				Name:      fields[0],
+				Current:   fields[1],
+				Candidate: fields[3],
+			})
+			continue
+		}
+
+		// apt: name/suite candidate arch [upgradable from: current]
+		// apk: name-candidate arch {origin} (license) [upgradable from: name-current]
+		if idx := strings.Index(line, "[upgradable from: "); idx != -1 {
+			current := strings.TrimSuffix(line[idx+len("[upgradable from: "):], "]")
+			if name, suite, ok := strings.Cut(fields[0], "/"); ok && suite != "" && len(fields) > 1 {
+				packages = append(packages, UpgradablePackage{
+					Name:      name,
+					Current:   current,
+					Candidate: fields[1],
+				})
+				continue
+			}
+
+			name, candidate := splitApkPackage(fields[0])
+			_, current = splitApkPackage(current)
+			packages = append(packages, UpgradablePackage{
+				Name:      name,
+				Current:   current,
+				Candidate: candidate,
+			})
+			continue
+		}
+
+		// dnf: name.arch candidate repository
+		if len(fields) == 3 && strings.Contains(fields[0], ".") && strings.ContainsAny(fields[1], "0123456789") {
+			packages = append(packages, UpgradablePackage{
+				Name:      trimArch(fields[0]),
+				Candidate: fields[1],
+			})
+		}
+	}
+
+	return packages
+}
+
+// splitApkPackage splits an apk package string in the name-version-rN
+// format into name and version.
+func splitApkPackage(pkg string) (string, string) {
+	parts := strings.Split(pkg, "-")
+	if len(parts) < 3 {
+		return pkg, ""
+	}
+
+	return strings.Join(parts[:len(parts)-2], "-"), strings.Join(parts[len(parts)-2:], "-")
+}
+
+// SearchResult represents a package found by a package manager search,
+// Version is empty when the package manager does not report it.
+type SearchResult struct {
+	Name    string
+	Version string
+}
+
+// knownArchs lists the architecture suffixes used by rpm based package
+// managers in package names, e.g. "vim.x86_64".
+var knownArchs = []string{"x86_64", "noarch", "i686", "aarch64", "armv7hl", "ppc64le", "s390x", "src"}
+
+// ParseSearch parses the output of a package manager CmdSearch. The formats
+// of apt, dnf, pacman, apk and zypper are supported, lines which cannot be
+// recognised (headers, descriptions) are skipped.
+func ParseSearch(output string) []SearchResult {
+	results := make([]SearchResult, 0)
+	nameCol, versionCol := -1, -1
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// zypper: S | Name | Type | Version | Arch | Repository
+		if strings.Contains(line, "|") {
+			cols := strings.Split(line, "|")
+			for i := range cols {
+				cols[i] = strings.TrimSpace(cols[i])
+			}
+
+			if nameCol == -1 {
+				for i, col := range cols {
+					switch col {
+					case "Name":
+						nameCol = i
+					case "Version":
+						versionCol = i
+					}
+				}
+				continue
+			}
+
+			if nameCol < len(cols) && cols[nameCol] != "" {
+				result := SearchResult{Name: cols[nameCol]}
+				if versionCol != -1 && versionCol < len(cols) {
+					result.Version = cols[versionCol]
+				}
+				results = append(results, result)
+			}
+			continue
+		}
+
+		// descriptions are indented below the package line, dnf5 indents
+		// the results instead, in the name.arch summary format
+		if line[0] == ' ' || line[0] == '\t' {
+			fields := strings.Fields(line)
+			if len(fields) > 0 && trimArch(fields[0]) != fields[0] {
+				results = append(results, SearchResult{Name: trimArch(fields[0])})
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		// dnf: name.arch : summary
+		if len(fields) >= 2 && fields[1] == ":" {
+			results = append(results, SearchResult{Name: trimArch(fields[0])})
+			continue
+		}
+
+		if before, after, ok := strings.Cut(fields[0], "/"); ok && len(fields) >= 2 {
+			// apt: name/suite version arch [installed]
+			if len(fields) >= 3 && !strings.HasPrefix(fields[2], "[") && !strings.HasPrefix(fields[2], "(") {
+				results = append(results, SearchResult{Name: before, Version: fields[1]})
+				continue
+			}
+
+			// pacman: repository/name version [installed] (group)
+			results = append(results, SearchResult{Name: after, Version: fields[1]})
+			continue
+		}
+
+		// apk: name-version-rN
+		if len(fields) == 1 {
+			name, version := splitApkPackage(fields[0])
+			if strings.HasPrefix(version[strings.LastIndex(version, "-")+1:], "r") {
+				results = append(results, SearchResult{Name: name, Version: version})
+			}
+		}
+	}
+
+	return results
+}
+
+// trimArch removes a known architecture suffix from a package name.
+func trimArch(name string) string {
+	idx := strings.LastIndex(name, ".")
+	if idx == -1 {
+		return name
+	}
+
+	for _, arch := range knownArchs {
+		if name[idx+1:] == arch {
+			return name[:idx]
+		}
+	}
+
+	return name
+}
+
+// ParseOwner parses the output of a package manager CmdOwns and returns the
+// name of the package owning the file. The formats of dpkg, rpm, pacman and
+// apk are supported.
+func ParseOwner(output string) string {
+	line := strings.TrimSpace(strings.Split(strings.TrimSpace(output), "\n")[0])
+
+	// pacman, apk: /path is owned by name version
+	if _, after, ok := strings.Cut(line, " is owned by "); ok {
+		fields := strings.Fields(after)
+		if len(fields) == 0 {
+			return ""
+		}
+
+		name, version := splitApkPackage(fields[0])
+		if len(fields) == 1 && strings.HasPrefix(version[strings.LastIndex(version, "-")+1:], "r") {
+			return name
+		}
+		return fields[0]
+	}
+
+	// dpkg: name[:arch]: /path
+	if before, _, ok := strings.Cut(line, ": "); ok {
+		name, _, _ := strings.Cut(before, ":")
+		return name
+	}
+
+	// rpm: name-version-release.arch
+	name, _ := splitApkPackage(trimArch(line))
+	return name
+}
+
+// installedHeaders are the prefixes of the lines printed by the package
+// managers before the list of installed packages.
+var installedHeaders = []string{"Listing...", "Installed Packages", "Installed packages", "Loading repositor", "Reading installed packages", "Last metadata", "Updating and loading", "Repositories loaded", "WARNING"}
+
+// ParseInstalled parses the output of a package manager CmdList and returns
+// the names of the installed packages. The formats of apt, dnf, pacman, apk
+// and zypper are supported, headers and notices are skipped.
+func ParseInstalled(output string) []string {
+	packages := make([]string, 0)
+	nameCol := -1
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		isHeader := false
+		for _, header := range installedHeaders {
+			if strings.HasPrefix(line, header) {
+				isHeader = true
+				break
+			}
+		}
+		if isHeader {
+			continue
+		}
+
+		// zypper: S | Repository | Name | Version | Arch
+		if strings.Contains(line, "|") {
+			cols := strings.Split(line, "|")
+			for i := range cols {
+				cols[i] = strings.TrimSpace(cols[i])
+			}
+
+			if nameCol == -1 {
+				for i, col := range cols {
+					if col == "Name" {
+						nameCol = i
+					}
+				}
+				continue
+			}
+
+			if nameCol < len(cols) && cols[nameCol] != "" {
+				packages = append(packages, cols[nameCol])
+			}
+			continue
+		}
+
+		// separator lines of the zypper table
+		if strings.Trim(line, "-+") == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		// apt: name/suite,now version arch [installed]
+		if name, _, ok := strings.Cut(fields[0], "/"); ok {
+			packages = append(packages, name)
+			continue
+		}
+
+		// apk: name-version-rN arch {origin} (license) [installed]
+		if strings.HasSuffix(line, "[installed]") {
+			name, _ := splitApkPackage(fields[0])
+			packages = append(packages, name)
+			continue
+		}
+
+		// dnf: name.arch version repository, pacman: name version
+		packages = append(packages, trimArch(fields[0]))
+	}
+
+	return packages
+}
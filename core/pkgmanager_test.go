@@ -0,0 +1,288 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenCmdNonInteractive(t *testing.T) {
+	apt := &PkgManager{
+		Model:              2,
+		NeedSudo:           true,
+		CmdInstall:         "apt install",
+		CmdList:            "apt list --installed",
+		CmdOwns:            "dpkg -S",
+		NonInteractiveFlag: "-y",
+		NonInteractiveEnv:  "DEBIAN_FRONTEND=noninteractive",
+	}
+	zypper := &PkgManager{
+		Model:                2,
+		NeedSudo:             true,
+		CmdInstall:           "zypper install",
+		CmdSearch:            "zypper search",
+		NonInteractiveFlag:   "--non-interactive",
+		NonInteractiveGlobal: true,
+	}
+
+	tests := []struct {
+		name       string
+		pkgManager *PkgManager
+		assumeYes  bool
+		cmd        string
+		args       []string
+		want       []string
+	}{
+		{"interactive", apt, false, apt.CmdInstall, []string{"htop"}, []string{"sudo", "apt", "install", "htop"}},
+		{"install", apt, true, apt.CmdInstall, []string{"htop"}, []string{"sudo", "env", "DEBIAN_FRONTEND=noninteractive", "apt", "install", "-y", "htop"}},
+		{"list", apt, true, apt.CmdList, nil, []string{"sudo", "apt", "list", "--installed"}},
+		{"owns", apt, true, apt.CmdOwns, []string{"/usr/bin/htop"}, []string{"sudo", "dpkg", "-S", "/usr/bin/htop"}},
+		{"global flag", zypper, true, zypper.CmdInstall, []string{"htop"}, []string{"sudo", "zypper", "--non-interactive", "install", "htop"}},
+		{"global flag query", zypper, true, zypper.CmdSearch, []string{"htop"}, []string{"sudo", "zypper", "search", "htop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.pkgManager.genCmd(tt.assumeYes, tt.cmd, tt.args...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("genCmd() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// stockApt is the definition of apt shipped in /usr/share/apx, which does
+// not declare the non-interactive settings.
+const stockApt = `name: apt
+needsudo: true
+cmdautoremove: apt autoremove
+cmdclean: apt clean
+cmdinstall: apt install
+cmdlist: apt list
+cmdpurge: apt purge
+cmdremove: apt remove
+cmdsearch: apt search
+cmdshow: apt show
+cmdupdate: apt update
+cmdupgrade: apt upgrade
+model: 2
+builtin: true
+`
+
+func TestPkgManagerDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apt.yml")
+	err := os.WriteFile(path, []byte(stockApt), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apt, err := LoadPkgManagerFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := apt.genCmd(true, apt.CmdInstall, "htop")
+	want := []string{"sudo", "env", "DEBIAN_FRONTEND=noninteractive", "apt", "install", "-y", "htop"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("genCmd() = %q, want %q", got, want)
+	}
+
+	for _, name := range []string{"apt", "dnf", "pacman", "apk", "zypper"} {
+		stock := &PkgManager{Name: name, Model: 2}
+		stock.applyDefaults()
+		for _, op := range []string{PkgManagerOpListUpgradable, PkgManagerOpOwns} {
+			if !stock.Supports(op) {
+				t.Errorf("stock %s does not support %s", name, op)
+			}
+		}
+	}
+
+	if !apt.Supports(PkgManagerOpHold) || apt.CmdHold != "apt-mark hold" {
+		t.Errorf("CmdHold = %q, want the default of apt", apt.CmdHold)
+	}
+	legacy := &PkgManager{Name: "apt", Model: 1}
+	legacy.applyDefaults()
+	if legacy.CmdOwns != "" {
+		t.Errorf("applyDefaults() completed the commands of model 1")
+	}
+
+	// the settings of a definition are never completed
+	custom := &PkgManager{Name: "zypper", NonInteractiveFlag: "-n"}
+	custom.applyDefaults()
+	if custom.NonInteractiveGlobal {
+		t.Errorf("applyDefaults() changed the settings of the definition")
+	}
+}
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []SearchResult
+	}{
+		{
+			"apt",
+			"Sorting...\nFull Text Search...\nhtop/stable 3.2.2-2 amd64\n  interactive processes viewer\n\nbtop/stable,now 1.2.13-1 amd64 [installed]\n  Modern and colorful command line resource monitor\n",
+			[]SearchResult{{"htop", "3.2.2-2"}, {"btop", "1.2.13-1"}},
+		},
+		{
+			"apt crlf",
+			"htop/stable 3.2.2-2 amd64\r\n\r\n  interactive processes viewer\r\n",
+			[]SearchResult{{"htop", "3.2.2-2"}},
+		},
+		{
+			"dnf",
+			"Last metadata expiration check: 0:01:02 ago on Mon 19 Oct 2026 10:00:00 AM UTC.\n========================= Name Exactly Matched: htop =========================\nhtop.x86_64 : Interactive process viewer\n",
+			[]SearchResult{{Name: "htop"}},
+		},
+		{
+			"dnf5",
+			"Updating and loading repositories:\nRepositories loaded.\nMatched fields: name (exact)\n htop.x86_64\tInteractive process viewer\n",
+			[]SearchResult{{Name: "htop"}},
+		},
+		{
+			"pacman",
+			"extra/htop 3.3.0-1\n    Interactive process viewer\nextra/bashtop 0.9.25-2 [installed]\n    Linux resource monitor\n",
+			[]SearchResult{{"htop", "3.3.0-1"}, {"bashtop", "0.9.25-2"}},
+		},
+		{
+			"apk",
+			"htop-3.2.2-r1\nhtop-doc-3.2.2-r1\n",
+			[]SearchResult{{"htop", "3.2.2-r1"}, {"htop-doc", "3.2.2-r1"}},
+		},
+		{
+			"zypper",
+			"Loading repository data...\nReading installed packages...\n\nS | Name | Type    | Version   | Arch   | Repository\n--+------+---------+-----------+--------+-----------\n  | htop | package | 3.3.0-1.1 | x86_64 | repo-oss\n",
+			[]SearchResult{{"htop", "3.3.0-1.1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSearch(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSearch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUpgradable(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []UpgradablePackage
+	}{
+		{
+			"apt",
+			"Listing... Done\nhtop/stable 3.2.2-3 amd64 [upgradable from: 3.2.2-2]\n",
+			[]UpgradablePackage{{"htop", "3.2.2-2", "3.2.2-3"}},
+		},
+		{
+			"dnf",
+			"Last metadata expiration check: 0:10:00 ago on Mon 19 Oct 2026 10:00:00 AM UTC.\nAvailable Upgrades\nvim-enhanced.x86_64       2:9.1.083-1.fc40       updates\n",
+			[]UpgradablePackage{{Name: "vim-enhanced", Candidate: "2:9.1.083-1.fc40"}},
+		},
+		{
+			"pacman",
+			"htop 3.3.0-1 -> 3.3.0-2\n",
+			[]UpgradablePackage{{"htop", "3.3.0-1", "3.3.0-2"}},
+		},
+		{
+			"apk",
+			"htop-3.2.2-r2 x86_64 {htop} (GPL-2.0-or-later) [upgradable from: htop-3.2.2-r1]\n",
+			[]UpgradablePackage{{"htop", "3.2.2-r1", "3.2.2-r2"}},
+		},
+		{
+			"zypper",
+			"Loading repository data...\nReading installed packages...\nS | Repository | Name | Current Version | Available Version | Arch\n--+------------+------+-----------------+-------------------+-------\nv | repo-oss   | htop | 3.2.2-1.1       | 3.3.0-1.1         | x86_64\n",
+			[]UpgradablePackage{{"htop", "3.2.2-1.1", "3.3.0-1.1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseUpgradable(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseUpgradable() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInstalled(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			"apt",
+			"Listing... Done\nhtop/stable,now 3.2.2-2 amd64 [installed]\nlibc6/stable,now 2.36-9 amd64 [installed,automatic]\n",
+			[]string{"htop", "libc6"},
+		},
+		{
+			"dnf",
+			"Installed Packages\nhtop.x86_64                3.3.0-2.fc40               @updates\nbash.x86_64                5.2.26-3.fc40              @anaconda\n",
+			[]string{"htop", "bash"},
+		},
+		{
+			"pacman",
+			"htop 3.3.0-1\nbash 5.2.026-2\n",
+			[]string{"htop", "bash"},
+		},
+		{
+			"apk",
+			"htop-3.2.2-r1 x86_64 {htop} (GPL-2.0-or-later) [installed]\nmusl-1.2.4-r2 x86_64 {musl} (MIT) [installed]\n",
+			[]string{"htop", "musl"},
+		},
+		{
+			"zypper",
+			"Loading repository data...\nReading installed packages...\n\nS  | Name | Summary                    | Type\n---+------+----------------------------+--------\ni+ | htop | Interactive process viewer | package\n",
+			[]string{"htop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseInstalled(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseInstalled() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOwner(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"dpkg", "htop: /usr/bin/htop\n", "htop"},
+		{"dpkg multiarch", "libc6:amd64: /lib/x86_64-linux-gnu/libc.so.6\n", "libc6"},
+		{"rpm", "htop-3.3.0-2.fc40.x86_64\n", "htop"},
+		{"rpm dashed name", "vim-enhanced-9.1.083-1.fc40.x86_64\n", "vim-enhanced"},
+		{"pacman", "/usr/bin/htop is owned by htop 3.3.0-1\n", "htop"},
+		{"apk", "/usr/bin/htop is owned by htop-3.2.2-r1\n", "htop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseOwner(tt.output)
+			if got != tt.want {
+				t.Errorf("ParseOwner() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
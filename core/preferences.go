@@ -0,0 +1,106 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// SubSystemPreferences holds the user choices about subsystems: the
+// default one, targeted by the top-level commands, and the aliases, short
+// names mapped to the full subsystem names.
+type SubSystemPreferences struct {
+	Default string
+	Aliases map[string]string
+}
+
+func subSystemPreferencesPath() string {
+	return filepath.Join(apx.Cnf.UserApxPath, "subsystems.json")
+}
+
+// LoadSubSystemPreferences loads the subsystem preferences of the user,
+// returning empty ones if none were saved yet.
+func LoadSubSystemPreferences() (*SubSystemPreferences, error) {
+	preferences := &SubSystemPreferences{Aliases: map[string]string{}}
+
+	data, err := os.ReadFile(subSystemPreferencesPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return preferences, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, preferences)
+	if err != nil {
+		return nil, err
+	}
+
+	if preferences.Aliases == nil {
+		preferences.Aliases = map[string]string{}
+	}
+
+	return preferences, nil
+}
+
+// Save writes the subsystem preferences to the user apx directory.
+func (p *SubSystemPreferences) Save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(subSystemPreferencesPath()), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(subSystemPreferencesPath(), data, 0644)
+}
+
+// Resolve returns the subsystem name the given alias refers to, or the name
+// itself if it is not an alias.
+func (p *SubSystemPreferences) Resolve(name string) string {
+	if target, ok := p.Aliases[name]; ok {
+		return target
+	}
+	return name
+}
+
+// RenameSubSystem updates the default and the aliases referring to the
+// subsystem after it has been renamed.
+func (p *SubSystemPreferences) RenameSubSystem(oldName string, newName string) {
+	if p.Default == oldName {
+		p.Default = newName
+	}
+
+	for alias, target := range p.Aliases {
+		if target == oldName {
+			p.Aliases[alias] = newName
+		}
+	}
+}
+
+// ForgetSubSystem removes the default and the aliases referring to the
+// subsystem after it has been removed.
+func (p *SubSystemPreferences) ForgetSubSystem(name string) {
+	if p.Default == name {
+		p.Default = ""
+	}
+
+	for alias, target := range p.Aliases {
+		if target == name {
+			delete(p.Aliases, alias)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProgressPulling      = "pulling"
+	ProgressCreating     = "creating"
+	ProgressInitializing = "initializing"
+	ProgressInstalling   = "installing"
+	ProgressExporting    = "exporting"
+	ProgressDone         = "done"
+	ProgressFailed       = "failed"
+)
+
+const (
+	// ProgressUnitBytes means Current and Total are byte counts.
+	ProgressUnitBytes = "bytes"
+
+	// ProgressUnitLayers means Current and Total are image layer counts,
+	// used when the engine does not report the size of the layers.
+	ProgressUnitLayers = "layers"
+)
+
+// ProgressEvent represents a step of a long operation on a subsystem.
+// Current, Total and Unit are only set for the steps with a measurable
+// progress, like pulling an image.
+type ProgressEvent struct {
+	Time      time.Time
+	Subsystem string
+	Phase     string
+	Message   string `json:",omitempty"`
+	Current   int64  `json:",omitempty"`
+	Total     int64  `json:",omitempty"`
+	Unit      string `json:",omitempty"`
+}
+
+// Percent returns the completion of the step, or -1 if it is unknown.
+func (e ProgressEvent) Percent() int {
+	if e.Total <= 0 {
+		return -1
+	}
+	return int(e.Current * 100 / e.Total)
+}
+
+var (
+	progressHandler     func(ProgressEvent)
+	progressHandlerLock sync.Mutex
+)
+
+// SetProgressHandler sets the function receiving the progress events of
+// long operations, like creating a subsystem. While a handler is set, the
+// output of distrobox and of the container engine is parsed into events
+// instead of being printed. Pass nil to restore the default behaviour.
+func SetProgressHandler(handler func(ProgressEvent)) {
+	progressHandlerLock.Lock()
+	defer progressHandlerLock.Unlock()
+	progressHandler = handler
+}
+
+func emitProgress(event ProgressEvent) {
+	progressHandlerLock.Lock()
+	handler := progressHandler
+	progressHandlerLock.Unlock()
+
+	if handler == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	handler(event)
+}
+
+// progressDone reports the end of the operation on the subsystem.
+func progressDone(subSystem string) {
+	emitProgress(ProgressEvent{Subsystem: subSystem, Phase: ProgressDone})
+}
+
+func hasProgressHandler() bool {
+	progressHandlerLock.Lock()
+	defer progressHandlerLock.Unlock()
+	return progressHandler != nil
+}
+
+var (
+	ansiRegex     = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+	statusRegex   = regexp.MustCompile(`\s*\[\s*(OK|ERR)\s*\]\s*$`)
+	sizesRegex    = regexp.MustCompile(`([\d.]+)\s*([kKMGTP]?i?B)\s*/\s*([\d.]+)\s*([kKMGTP]?i?B)`)
+	podmanBlobRe  = regexp.MustCompile(`^Copying blob (?:sha256:)?([0-9a-f]+)(.*)$`)
+	dockerLayerRe = regexp.MustCompile(`^([0-9a-f]{12}): (.*)$`)
+)
+
+type layerProgress struct {
+	current int64
+	total   int64
+	done    bool
+}
+
+// progressParser turns the output of distrobox and of the container engine
+// into progress events for a subsystem.
+type progressParser struct {
+	subSystem string
+	phase     string
+	buffer    []byte
+	layers    map[string]*layerProgress
+	order     []string
+}
+
+// newProgressParser returns a parser for the subsystem, or nil if nobody
+// is listening for progress events.
+func newProgressParser(subSystem string) *progressParser {
+	if !hasProgressHandler() {
+		return nil
+	}
+	return &progressParser{subSystem: subSystem}
+}
+
+// setPhase starts a new phase, the parser can be nil.
+func (p *progressParser) setPhase(phase string, message string) {
+	if p == nil {
+		return
+	}
+
+	p.phase = phase
+	p.layers = map[string]*layerProgress{}
+	p.order = []string{}
+	p.emit(message, 0, 0, "")
+}
+
+// fail reports the error of the current phase, the parser can be nil.
+func (p *progressParser) fail(err error) {
+	if p == nil {
+		return
+	}
+	p.setPhase(ProgressFailed, err.Error())
+}
+
+func (p *progressParser) emit(message string, current int64, total int64, unit string) {
+	emitProgress(ProgressEvent{
+		Subsystem: p.subSystem,
+		Phase:     p.phase,
+		Message:   message,
+		Current:   current,
+		Total:     total,
+		Unit:      unit,
+	})
+}
+
+// Write splits the output in lines, including the ones ended by a carriage
+// return which are used by progress bars.
+func (p *progressParser) Write(data []byte) (int, error) {
+	p.buffer = append(p.buffer, data...)
+	for {
+		i := bytes.IndexAny(p.buffer, "\r\n")
+		if i < 0 {
+			break
+		}
+
+		line := strings.TrimSpace(ansiRegex.ReplaceAllString(string(p.buffer[:i]), ""))
+		p.buffer = p.buffer[i+1:]
+		if line != "" {
+			p.parseLine(line)
+		}
+	}
+	return len(data), nil
+}
+
+func (p *progressParser) parseLine(line string) {
+	if p.phase == ProgressPulling {
+		p.parsePullLine(line)
+		return
+	}
+
+	line = strings.TrimPrefix(statusRegex.ReplaceAllString(line, ""), "distrobox: ")
+	line = strings.TrimRight(line, ".… ")
+	if line == "" {
+		return
+	}
+
+	if p.phase == ProgressInitializing && strings.Contains(strings.ToLower(line), "additional packages") {
+		p.setPhase(ProgressInstalling, line)
+		return
+	}
+
+	p.emit(line, 0, 0, "")
+}
+
+// parsePullLine handles the pull output of podman ("Copying blob <id> ...")
+// and docker ("<id>: <status>"). Sizes are only reported by some engine
+// versions, the layer count is used otherwise.
+func (p *progressParser) parsePullLine(line string) {
+	var id, status string
+	if match := podmanBlobRe.FindStringSubmatch(line); match != nil {
+		id, status = match[1], match[2]
+	} else if match := dockerLayerRe.FindStringSubmatch(line); match != nil {
+		id, status = match[1], match[2]
+	} else {
+		return
+	}
+
+	layer, ok := p.layers[id]
+	if !ok {
+		layer = &layerProgress{}
+		p.layers[id] = layer
+		p.order = append(p.order, id)
+	}
+
+	if sizes := sizesRegex.FindStringSubmatch(status); sizes != nil {
+		layer.current = parseSize(sizes[1], sizes[2])
+		layer.total = parseSize(sizes[3], sizes[4])
+	}
+
+	lowerStatus := strings.ToLower(status)
+	for _, done := range []string{"done", "skipped", "already exists", "pull complete"} {
+		if strings.Contains(lowerStatus, done) {
+			layer.done = true
+			layer.current = layer.total
+		}
+	}
+
+	var current, total, doneLayers int64
+	sized := true
+	for _, id := range p.order {
+		layer := p.layers[id]
+		if layer.total == 0 && !layer.done {
+			sized = false
+		}
+		current += layer.current
+		total += layer.total
+		if layer.done {
+			doneLayers++
+		}
+	}
+
+	if sized && total > 0 {
+		p.emit("", current, total, ProgressUnitBytes)
+		return
+	}
+	p.emit("", doneLayers, int64(len(p.order)), ProgressUnitLayers)
+}
+
+// parseSize converts a size like 12.5MiB or 3MB in bytes.
+func parseSize(value string, unit string) int64 {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+
+	base := 1000.0
+	if strings.Contains(unit, "i") {
+		base = 1024
+	}
+
+	switch strings.ToUpper(unit[:1]) {
+	case "K":
+		number *= base
+	case "M":
+		number *= base * base
+	case "G":
+		number *= base * base * base
+	case "T":
+		number *= base * base * base * base
+	case "P":
+		number *= base * base * base * base * base
+	}
+
+	return int64(number)
+}
@@ -0,0 +1,155 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"testing"
+)
+
+// collectProgress sets a progress handler for the duration of the test and
+// returns the events it received.
+func collectProgress(t *testing.T) *[]ProgressEvent {
+	t.Helper()
+
+	events := []ProgressEvent{}
+	SetProgressHandler(func(event ProgressEvent) {
+		events = append(events, event)
+	})
+	t.Cleanup(func() { SetProgressHandler(nil) })
+
+	return &events
+}
+
+func TestProgressParserWrite(t *testing.T) {
+	events := collectProgress(t)
+
+	p := newProgressParser("dev")
+	p.setPhase(ProgressCreating, "")
+
+	// lines can be split across writes, and progress bars end with \r
+	for _, chunk := range []string{"\x1b[1mCreating 'dev' using image", " debian:12  \x1b[0m[ OK ]\n", "\r\n", "Progress 1\rProgress 2\r", "partial"} {
+		n, err := p.Write([]byte(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+
+	want := []string{"", "Creating 'dev' using image debian:12", "Progress 1", "Progress 2"}
+	if len(*events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(*events), *events, len(want))
+	}
+	for i, event := range *events {
+		if event.Subsystem != "dev" || event.Phase != ProgressCreating || event.Message != want[i] {
+			t.Errorf("event %d = %+v, want message %q", i, event, want[i])
+		}
+	}
+}
+
+func TestProgressParserInstalling(t *testing.T) {
+	events := collectProgress(t)
+
+	p := newProgressParser("dev")
+	p.setPhase(ProgressInitializing, "")
+	_, _ = p.Write([]byte("Installing additional packages...\n"))
+
+	last := (*events)[len(*events)-1]
+	if last.Phase != ProgressInstalling || last.Message != "Installing additional packages" {
+		t.Errorf("last event = %+v, want the installing phase", last)
+	}
+}
+
+func TestParsePullLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  ProgressEvent
+	}{
+		{
+			"podman sizes",
+			[]string{
+				"Copying blob sha256:aaaa [=>---] 1.0MiB / 4.0MiB",
+				"Copying blob bbbb [====>] 2.0MiB / 4.0MiB",
+			},
+			ProgressEvent{Current: 3 * 1024 * 1024, Total: 8 * 1024 * 1024, Unit: ProgressUnitBytes},
+		},
+		{
+			"podman done",
+			[]string{
+				"Copying blob aaaa [=>---] 1.0MiB / 4.0MiB",
+				"Copying blob aaaa done",
+			},
+			ProgressEvent{Current: 4 * 1024 * 1024, Total: 4 * 1024 * 1024, Unit: ProgressUnitBytes},
+		},
+		{
+			"podman without sizes",
+			[]string{
+				"Copying blob aaaa",
+				"Copying blob bbbb",
+				"Copying blob aaaa done",
+			},
+			ProgressEvent{Current: 1, Total: 2, Unit: ProgressUnitLayers},
+		},
+		{
+			"docker without sizes",
+			[]string{
+				"0123456789ab: Pulling fs layer",
+				"ba9876543210: Pulling fs layer",
+				"0123456789ab: Pull complete",
+			},
+			ProgressEvent{Current: 1, Total: 2, Unit: ProgressUnitLayers},
+		},
+		{
+			// layers already available do not count in the download
+			"docker existing layer",
+			[]string{
+				"0123456789ab: Already exists",
+				"ba9876543210: Downloading  12.5MB/25MB",
+			},
+			ProgressEvent{Current: 12500000, Total: 25000000, Unit: ProgressUnitBytes},
+		},
+		{
+			"docker sizes",
+			[]string{
+				"0123456789ab: Downloading  12.5MB / 25MB",
+			},
+			ProgressEvent{Current: 12500000, Total: 25000000, Unit: ProgressUnitBytes},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := collectProgress(t)
+
+			p := newProgressParser("dev")
+			p.setPhase(ProgressPulling, "debian:12")
+			for _, line := range tt.lines {
+				p.parsePullLine(line)
+			}
+
+			got := (*events)[len(*events)-1]
+			if got.Phase != ProgressPulling || got.Current != tt.want.Current || got.Total != tt.want.Total || got.Unit != tt.want.Unit {
+				t.Errorf("last event = %+v, want %d/%d %s", got, tt.want.Current, tt.want.Total, tt.want.Unit)
+			}
+		})
+	}
+}
+
+func TestParsePullLineIgnoresOtherLines(t *testing.T) {
+	events := collectProgress(t)
+
+	p := newProgressParser("dev")
+	p.setPhase(ProgressPulling, "debian:12")
+	p.parsePullLine("Trying to pull docker.io/library/debian:12...")
+	p.parsePullLine("Writing manifest to image destination")
+
+	if len(*events) != 1 {
+		t.Errorf("got %d events %+v, want only the phase one", len(*events), *events)
+	}
+}
@@ -24,7 +24,10 @@ type Stack struct {
 	Base       string
 	Packages   []string
 	PkgManager string
-	BuiltIn    bool // If true, the stack is built-in (stored in /usr/share/apx/stacks) and cannot be removed by the user
+	BuiltIn    bool              // If true, the stack is built-in (stored in /usr/share/apx/stacks) and cannot be removed by the user
+	Volumes    []Volume          `yaml:",omitempty"` // Mounted in every subsystem created from the stack
+	Env        map[string]string `yaml:",omitempty"` // Set in every subsystem created from the stack
+	PullPolicy string            `yaml:",omitempty"` // Overrides the global pull policy for the base image
 }
 
 // NewStack creates a new Stack instance.
@@ -38,14 +41,17 @@ func NewStack(name, base string, packages []string, pkgManager string, builtIn b
 	}
 }
 
-// LoadStack loads a stack from the specified path.
+// LoadStack loads a stack by name, looking for the user stacks first.
 func LoadStack(name string) (*Stack, error) {
 	usrStackFile := SelectYamlFile(apx.Cnf.UserStacksPath, name)
 	stack, err := LoadStackFromPath(usrStackFile)
-	if err != nil {
+	if errors.Is(err, ErrStackNotFound) {
 		stackFile := SelectYamlFile(apx.Cnf.StacksPath, name)
 		stack, err = LoadStackFromPath(stackFile)
 	}
+	if errors.Is(err, ErrStackNotFound) {
+		return nil, &NotFoundError{Err: ErrStackNotFound, Name: name}
+	}
 	return stack, err
 }
 
@@ -54,9 +60,13 @@ func LoadStackFromPath(path string) (*Stack, error) {
 	stack := &Stack{}
 
 	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, &NotFoundError{Err: ErrStackNotFound, Name: path}
+	}
 	if err != nil {
-		return nil, errors.New("stack not found")
+		return nil, err
 	}
+	defer f.Close()
 
 	data, err := io.ReadAll(f)
 	if err != nil {
@@ -65,11 +75,21 @@ func LoadStackFromPath(path string) (*Stack, error) {
 
 	err = yaml.Unmarshal(data, stack)
 	if err != nil {
-		return nil, err
+		return nil, &InvalidStackError{Path: path, Err: err}
+	}
+
+	switch {
+	case stack.Name == "":
+		return nil, &InvalidStackError{Path: path, Field: "name"}
+	case stack.Base == "":
+		return nil, &InvalidStackError{Path: path, Field: "base"}
+	case stack.PkgManager == "":
+		return nil, &InvalidStackError{Path: path, Field: "pkgmanager"}
 	}
 
-	if stack.Name == "" || stack.Base == "" || stack.PkgManager == "" {
-		return nil, errors.New("invalid stack file")
+	err = ValidatePullPolicy(stack.PullPolicy)
+	if err != nil {
+		return nil, &InvalidStackError{Path: path, Err: err}
 	}
 
 	return stack, nil
@@ -100,7 +120,7 @@ func (stack *Stack) GetPkgManager() (*PkgManager, error) {
 // Remove removes the stack file.
 func (stack *Stack) Remove() error {
 	if stack.BuiltIn {
-		return errors.New("cannot remove built-in stack")
+		return ErrStackBuiltIn
 	}
 
 	filePath := SelectYamlFile(apx.Cnf.UserStacksPath, stack.Name)
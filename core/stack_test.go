@@ -0,0 +1,65 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const stackWithOptions = `name: dev
+base: docker.io/library/debian:bookworm
+packages: []
+pkgmanager: apt
+volumes:
+  - source: /srv/data
+    destination: /data
+    readonly: true
+env:
+  JAVA_HOME: /usr/lib/jvm/java-21
+pullpolicy: missing
+`
+
+func TestLoadStackKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev.yml")
+	err := os.WriteFile(path, []byte(stackWithOptions), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stack, err := LoadStackFromPath(path)
+	if err != nil {
+		t.Fatalf("LoadStackFromPath() error = %v", err)
+	}
+	if len(stack.Volumes) != 1 || !stack.Volumes[0].ReadOnly {
+		t.Errorf("Volumes = %+v, want a read-only volume", stack.Volumes)
+	}
+	if stack.Env["JAVA_HOME"] != "/usr/lib/jvm/java-21" {
+		t.Errorf("Env = %v, want JAVA_HOME", stack.Env)
+	}
+	if stack.PullPolicy != PullPolicyMissing {
+		t.Errorf("PullPolicy = %q, want %q", stack.PullPolicy, PullPolicyMissing)
+	}
+
+	// saved stacks use the same lowercase keys as pkgmanager
+	data, err := yaml.Marshal(stack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"pullpolicy: missing", "readonly: true"} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("marshalled stack does not contain %q:\n%s", key, data)
+		}
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -22,6 +25,7 @@ import (
 */
 
 type SubSystem struct {
+	ID                   string
 	InternalName         string
 	Name                 string
 	Stack                *Stack
@@ -35,12 +39,26 @@ type SubSystem struct {
 	HasNvidiaIntegration bool
 	Hostname             string
 	AdditionalArgs       []string
+	Limits               ResourceLimits
+	Volumes              []Volume
+	Env                  map[string]string
+	LastUpgrade          *time.Time
+
+	// BinPaths lists the directories, other than the shared one, where
+	// binaries were exported to.
+	BinPaths []string
 }
 
 func NewSubSystem(name string, stack *Stack, home string, hasInit bool, isManaged bool, isRootfull bool, isUnshared bool, hasNvidiaIntegration bool, hostname string, additionalArgs ...string) (*SubSystem, error) {
-	internalName := genInternalName(name)
+	err := ValidateSubSystemName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	id := genSubSystemID()
 	return &SubSystem{
-		InternalName:         internalName,
+		ID:                   id,
+		InternalName:         genInternalName(name, id),
 		Name:                 name,
 		Stack:                stack,
 		Home:                 home,
@@ -54,60 +72,166 @@ func NewSubSystem(name string, stack *Stack, home string, hasInit bool, isManage
 	}, nil
 }
 
-func genInternalName(name string) string {
-	return fmt.Sprintf("apx-%s", strings.ReplaceAll(strings.ToLower(name), " ", "-"))
+// subSystemNameMaxLength is the maximum length of a subsystem name.
+const subSystemNameMaxLength = 64
+
+// ValidateSubSystemName checks that the name can be used for a subsystem,
+// i.e. it is not empty, not too long, contains no control characters and
+// cannot be mistaken for a flag.
+func ValidateSubSystemName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return &InvalidNameError{Name: name, Reason: "the name is empty"}
+	}
+
+	if strings.TrimSpace(name) != name {
+		return &InvalidNameError{Name: name, Reason: "the name starts or ends with a space"}
+	}
+
+	if utf8.RuneCountInString(name) > subSystemNameMaxLength {
+		return &InvalidNameError{Name: name, Reason: fmt.Sprintf("the name is longer than %d characters", subSystemNameMaxLength)}
+	}
+
+	if strings.HasPrefix(name, "-") {
+		return &InvalidNameError{Name: name, Reason: "the name starts with a dash"}
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) || r == '/' {
+			return &InvalidNameError{Name: name, Reason: "the name contains an invalid character"}
+		}
+	}
+
+	return nil
+}
+
+// genSubSystemID returns a short random identifier, stored in the
+// container labels and used to make the internal names unique.
+func genSubSystemID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")[:8]
 }
 
-func findExportedBinaries(internalName string) map[string]map[string]string {
+// genInternalName returns the container name for the subsystem. The name
+// is reduced to lowercase ASCII letters, digits and dashes, which every
+// engine accepts, and the id is appended so that names which reduce to the
+// same string, like "Dev Box" and "dev-box", do not collide.
+func genInternalName(name string, id string) string {
+	var sanitized strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sanitized.WriteRune(r)
+		} else if !strings.HasSuffix(sanitized.String(), "-") {
+			sanitized.WriteRune('-')
+		}
+	}
+
+	base := strings.Trim(sanitized.String(), "-")
+	if base == "" {
+		base = "subsystem"
+	}
+
+	return fmt.Sprintf("apx-%s-%s", base, id)
+}
+
+// findSubSystemContainer returns the container of the subsystem with the
+// given name, matching its name label.
+func findSubSystemContainer(dbox *dbox, name string, isRootFull bool) (*dboxContainer, error) {
+	containers, err := dbox.ListContainers(isRootFull)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, container := range containers {
+		if container.Labels["manager"] == "apx" && container.Labels["name"] == name && len(container.Names) > 0 {
+			return &container, nil
+		}
+	}
+
+	return nil, &NotFoundError{Err: ErrSubsystemNotFound, Name: name}
+}
+
+// exportedBinDir returns the default directory where the binaries are
+// exported, shared by all the subsystems.
+func exportedBinDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return map[string]map[string]string{}
+		return ""
 	}
-	binPath := filepath.Join(home, ".local", "bin")
-	if _, err := os.Stat(binPath); os.IsNotExist(err) {
-		return map[string]map[string]string{}
+	return filepath.Join(home, ".local", "bin")
+}
+
+// exportedBinDirs returns the directories where the binaries of the
+// subsystem are exported: the shared one and those recorded by ExportBin.
+func exportedBinDirs(internalName string) []string {
+	dirs := []string{}
+	if dir := exportedBinDir(); dir != "" {
+		dirs = append(dirs, dir)
 	}
-	binDir := os.DirFS(binPath)
 
-	binaries := map[string]map[string]string{}
-	err = fs.WalkDir(binDir, ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
+	metadata, err := loadSubSystemMetadata(internalName)
+	if err != nil {
+		return dirs
+	}
 
-		file, err := os.Open(filepath.Join(binPath, path))
-		if err != nil {
-			return err
+	for _, dir := range metadata.BinPaths {
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
 		}
-		defer file.Close()
+	}
 
-		var header = "#!/bin/sh\n# distrobox_binary\n# name: " + internalName
-		var maxTokenSize = len(header)
-		var buffer = make([]byte, maxTokenSize)
+	return dirs
+}
 
-		n, err := file.Read(buffer)
-		if err != nil && err != io.EOF {
-			return err
+// findExportedBinaryPaths returns the paths of the binary wrappers
+// exported by the subsystem, in all its export directories.
+func findExportedBinaryPaths(internalName string) []string {
+	header := "#!/bin/sh\n# distrobox_binary\n# name: " + internalName
+	paths := []string{}
+
+	for _, binPath := range exportedBinDirs(internalName) {
+		if _, err := os.Stat(binPath); os.IsNotExist(err) {
+			continue
 		}
 
-		buffer = buffer[:n]
+		// a missing or unreadable bin directory means nothing was exported
+		_ = fs.WalkDir(os.DirFS(binPath), ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
 
-		if string(buffer) == header {
-			name := filepath.Base(path)
-			binaries[name] = map[string]string{
-				"Exec": path,
-				"Name": name,
+			file, err := os.Open(filepath.Join(binPath, path))
+			if err != nil {
+				return err
 			}
-		}
+			defer file.Close()
 
-		return nil
-	})
-	if err != nil {
-		fmt.Printf("error reading binaries: %s\n", err)
-		return map[string]map[string]string{}
+			buffer := make([]byte, len(header))
+			n, err := file.Read(buffer)
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			if string(buffer[:n]) == header {
+				paths = append(paths, filepath.Join(binPath, path))
+			}
+
+			return nil
+		})
+	}
+
+	return paths
+}
+
+func findExportedBinaries(internalName string) map[string]map[string]string {
+	binaries := map[string]map[string]string{}
+	for _, path := range findExportedBinaryPaths(internalName) {
+		name := filepath.Base(path)
+		binaries[name] = map[string]string{
+			"Exec": path,
+			"Name": name,
+		}
 	}
 
 	return binaries
@@ -186,6 +310,55 @@ func findExported(internalName string, name string) map[string]map[string]string
 }
 
 func (s *SubSystem) Create() error {
+	err := s.create(s.Stack.Base, s.Stack.Packages)
+	if err != nil {
+		return err
+	}
+
+	err = s.initialize()
+	if err != nil {
+		return err
+	}
+
+	// like the packages installed later, the stack packages get their apps
+	// exported, the ones without a desktop entry are skipped
+	if len(s.Stack.Packages) > 0 {
+		emitProgress(ProgressEvent{Subsystem: s.Name, Phase: ProgressExporting})
+		for _, pkg := range s.Stack.Packages {
+			_ = s.ExportDesktopEntry(pkg)
+		}
+	}
+
+	progressDone(s.Name)
+	return nil
+}
+
+// initialize starts the subsystem for the first time, so that distrobox
+// sets it up and installs the stack packages before it is used.
+func (s *SubSystem) initialize() error {
+	dbox, err := NewDbox()
+	if err != nil {
+		return err
+	}
+
+	progress := newProgressParser(s.Name)
+	if progress != nil {
+		dbox.output = progress
+	}
+
+	progress.setPhase(ProgressInitializing, "")
+	_, err = dbox.ContainerExec(s.InternalName, false, false, s.IsRootfull, false, s.envList(), "true")
+	if err != nil {
+		progress.fail(err)
+		return err
+	}
+
+	return nil
+}
+
+// create creates the subsystem container from the given image, installing
+// the additional packages.
+func (s *SubSystem) create(image string, packages []string) error {
 	dbox, err := NewDbox()
 	if err != nil {
 		return err
@@ -196,6 +369,10 @@ func (s *SubSystem) Create() error {
 		"name":  strings.ReplaceAll(s.Name, " ", "\\ "),
 	}
 
+	if s.ID != "" {
+		labels["id"] = s.ID
+	}
+
 	if s.IsManaged {
 		labels["managed"] = "true"
 	}
@@ -212,24 +389,48 @@ func (s *SubSystem) Create() error {
 		labels["nvidia"] = "true"
 	}
 
+	// stored so that the subsystem can be recreated with the same settings
+	if s.Home != "" {
+		labels["home"] = strings.ReplaceAll(s.Home, " ", "\\ ")
+	}
+
+	if s.Hostname != "" {
+		labels["hostname"] = s.Hostname
+	}
+
+	progress := newProgressParser(s.Name)
+	if progress != nil {
+		dbox.output = progress
+	}
+
+	progress.setPhase(ProgressPulling, image)
+	err = dbox.PullImage(image, s.Stack.GetPullPolicy(), s.IsRootfull)
+	if err != nil {
+		progress.fail(err)
+		return err
+	}
+
+	progress.setPhase(ProgressCreating, "")
 	err = dbox.CreateContainer(
 		s.InternalName,
-		s.Stack.Base,
-		s.Stack.Packages,
+		image,
+		packages,
 		s.Home,
 		labels,
+		s.Limits.EngineFlags(),
 		s.HasInit,
 		s.IsRootfull,
 		s.IsUnshared,
 		s.HasNvidiaIntegration,
 		s.Hostname,
-		s.AdditionalArgs...,
+		append(slices.Clone(s.AdditionalArgs), s.volumeArgs()...)...,
 	)
 	if err != nil {
+		progress.fail(err)
 		return err
 	}
 
-	return nil
+	return s.SaveMetadata()
 }
 
 func LoadSubSystem(name string, isRootFull bool) (*SubSystem, error) {
@@ -238,8 +439,7 @@ func LoadSubSystem(name string, isRootFull bool) (*SubSystem, error) {
 		return nil, err
 	}
 
-	internalName := genInternalName(name)
-	container, err := dbox.GetContainer(internalName, isRootFull)
+	container, err := findSubSystemContainer(dbox, name, isRootFull)
 	if err != nil {
 		return nil, err
 	}
@@ -248,8 +448,9 @@ func LoadSubSystem(name string, isRootFull bool) (*SubSystem, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &SubSystem{
-		InternalName: internalName,
+	subSystem := &SubSystem{
+		ID:           container.Labels["id"],
+		InternalName: container.Name(),
 		Name:         container.Labels["name"],
 		Stack:        stack,
 		Status:       container.Status,
@@ -257,7 +458,14 @@ func LoadSubSystem(name string, isRootFull bool) (*SubSystem, error) {
 		IsManaged:    container.Labels["managed"] == "true",
 		IsRootfull:   isRootFull,
 		IsUnshared:   container.Labels["unshared"] == "true",
-	}, nil
+
+		HasNvidiaIntegration: container.Labels["nvidia"] == "true",
+		Home:                 container.Labels["home"],
+		Hostname:             container.Labels["hostname"],
+	}
+	subSystem.applyMetadata()
+
+	return subSystem, nil
 }
 
 func ListSubSystems(includeManaged bool, includeRootFull bool) ([]*SubSystem, error) {
@@ -294,20 +502,22 @@ func ListSubSystems(includeManaged bool, includeRootFull bool) ([]*SubSystem, er
 			continue
 		}
 
+		// subsystems whose stack was removed cannot be managed anymore
 		stack, err := LoadStack(containerStack)
 		if err != nil {
-			log.Printf("Error loading stack %s: %s", containerStack, err)
 			continue
 		}
 
-		internalName := genInternalName(containerName)
+		internalName := container.Name()
 		subsystem := &SubSystem{
+			ID:               container.Labels["id"],
 			InternalName:     internalName,
 			Name:             containerName,
 			Stack:            stack,
 			Status:           container.Status,
 			ExportedPrograms: findExported(internalName, containerName),
 		}
+		subsystem.applyMetadata()
 
 		subsystems = append(subsystems, subsystem)
 	}
@@ -344,20 +554,22 @@ func ListSubsystemForStack(stackName string) ([]*SubSystem, error) {
 			continue
 		}
 
+		// subsystems whose stack was removed cannot be managed anymore
 		stack, err := LoadStack(containerStack)
 		if err != nil {
-			log.Printf("Error loading stack %s: %s", containerStack, err)
 			continue
 		}
 
-		internalName := genInternalName(containerName)
+		internalName := container.Name()
 		subsystem := &SubSystem{
+			ID:               container.Labels["id"],
 			InternalName:     internalName,
 			Name:             containerName,
 			Stack:            stack,
 			Status:           container.Status,
 			ExportedPrograms: findExported(internalName, containerName),
 		}
+		subsystem.applyMetadata()
 
 		subsystems = append(subsystems, subsystem)
 	}
@@ -371,7 +583,7 @@ func (s *SubSystem) Exec(captureOutput, detachedMode bool, args ...string) (stri
 		return "", err
 	}
 
-	out, err := dbox.ContainerExec(s.InternalName, captureOutput, false, s.IsRootfull, detachedMode, args...)
+	out, err := dbox.ContainerExec(s.InternalName, captureOutput, false, s.IsRootfull, detachedMode, s.envList(), args...)
 	if err != nil {
 		return "", err
 	}
@@ -388,7 +600,7 @@ func (s *SubSystem) Enter() error {
 	if err != nil {
 		return err
 	}
-	return dbox.ContainerEnter(s.InternalName, s.IsRootfull)
+	return dbox.ContainerEnter(s.InternalName, s.IsRootfull, s.envList())
 }
 
 func (s *SubSystem) Start() error {
@@ -416,13 +628,116 @@ func (s *SubSystem) Remove() error {
 	return dbox.ContainerDelete(s.InternalName, s.IsRootfull)
 }
 
+// Recreate recreates the subsystem container from a snapshot of its current
+// state, applying the settings which can only be set at creation. The new
+// container is created next to the current one, which is only removed once
+// the exports use the new one. Failures are reported as a RecreateError.
+func (s *SubSystem) Recreate() error {
+	dbox, err := NewDbox()
+	if err != nil {
+		return err
+	}
+
+	image := snapshotImageName(s.InternalName)
+	err = dbox.ContainerCommit(s.InternalName, image, s.IsRootfull)
+	if err != nil {
+		return &RecreateError{Step: RecreateStepSnapshot, Snapshot: image, Err: err}
+	}
+
+	recreated := s.replacement()
+
+	// the snapshot already contains the stack packages
+	err = recreated.create(image, nil)
+	if err != nil {
+		recreated.discard()
+		return &RecreateError{Step: RecreateStepCreate, Snapshot: image, Err: err}
+	}
+
+	err = s.moveExportsTo(&recreated)
+	if err != nil {
+		return &RecreateError{Step: RecreateStepExports, Snapshot: image, Err: err}
+	}
+
+	err = s.replaceWith(&recreated)
+	if err != nil {
+		return &RecreateError{Step: RecreateStepRemove, Snapshot: image, Err: err}
+	}
+
+	progressDone(s.Name)
+	return nil
+}
+
+// replacement returns a copy of the subsystem with a new ID, so that its
+// container can be created next to the current one.
+func (s *SubSystem) replacement() SubSystem {
+	replacement := *s
+	replacement.ID = genSubSystemID()
+	replacement.InternalName = genInternalName(s.Name, replacement.ID)
+	replacement.Status = ""
+	replacement.ExportedPrograms = nil
+	return replacement
+}
+
+// discard removes the container, the metadata and the snapshot image of a
+// replacement or a clone which could not be completed.
+func (s *SubSystem) discard() {
+	dbox, err := NewDbox()
+	if err == nil {
+		_ = dbox.ContainerDelete(s.InternalName, s.IsRootfull)
+		_ = dbox.ImageRemove(snapshotImageName(s.InternalName), s.IsRootfull)
+	}
+	_ = s.RemoveMetadata()
+}
+
+// moveExportsTo moves the exports of the subsystem to the replacement. If
+// they cannot be moved, the replacement is discarded and the subsystem is
+// left as it was.
+func (s *SubSystem) moveExportsTo(replacement *SubSystem) error {
+	err := s.moveExports(replacement)
+	if err != nil {
+		_ = replacement.moveExports(s)
+		replacement.discard()
+	}
+	return err
+}
+
+// replaceWith removes the current container of the subsystem, whose exports
+// were moved to the replacement, and makes the subsystem the replacement.
+func (s *SubSystem) replaceWith(replacement *SubSystem) error {
+	err := s.Remove()
+	if err != nil {
+		return err
+	}
+
+	err = s.RemoveMetadata()
+	if err != nil {
+		return err
+	}
+
+	// the replacement has its own ID, the packages are still there
+	err = copyTransactions(s, replacement, true)
+	if err != nil {
+		return err
+	}
+
+	*s = *replacement
+	return nil
+}
+
+// Reset recreates the subsystem from its stack, so the transactions of
+// the packages installed until then are forgotten.
 func (s *SubSystem) Reset() error {
 	err := s.Remove()
 	if err != nil {
 		return err
 	}
 
-	return s.Create()
+	err = s.Create()
+	if err != nil {
+		return err
+	}
+
+	return s.ForgetTransactions()
 }
 
 func (s *SubSystem) ExportDesktopEntry(app string) error {
@@ -431,7 +746,13 @@ func (s *SubSystem) ExportDesktopEntry(app string) error {
 		return err
 	}
 
-	return dbox.ContainerExportDesktopEntry(s.InternalName, app, fmt.Sprintf("on %s", s.Name), s.IsRootfull)
+	err = dbox.ContainerExportDesktopEntry(s.InternalName, app, fmt.Sprintf("on %s", s.Name), s.IsRootfull)
+	if err != nil || len(s.AllEnv()) == 0 {
+		return err
+	}
+
+	// the exported entries run without the environment variables
+	return s.updateExportsEnv()
 }
 
 func (s *SubSystem) ExportDesktopEntries(args ...string) (int, error) {
@@ -466,12 +787,12 @@ func (s *SubSystem) UnexportDesktopEntries(args ...string) (int, error) {
 
 func (s *SubSystem) ExportBin(binary string, exportPath string) error {
 	if !strings.HasPrefix(binary, "/") {
-		binaryPath, err := s.Exec(true, false, "which", binary)
+		binaryPath, err := s.Which(binary)
 		if err != nil {
 			return err
 		}
 
-		binary = strings.TrimSpace(binaryPath)
+		binary = binaryPath
 	}
 
 	binaryName := filepath.Base(binary)
@@ -481,13 +802,13 @@ func (s *SubSystem) ExportBin(binary string, exportPath string) error {
 		return err
 	}
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return err
+	if exportPath == "" {
+		exportPath = exportedBinDir()
 	}
 
-	if exportPath == "" {
-		exportPath = filepath.Join(homeDir, ".local", "bin")
+	err = s.addBinPath(exportPath)
+	if err != nil {
+		return err
 	}
 
 	joinedPath := filepath.Join(exportPath, binaryName)
@@ -518,7 +839,7 @@ func (s *SubSystem) ExportBin(binary string, exportPath string) error {
 			return err
 		}
 
-		return nil
+		return s.updateExportEnv(filepath.Join(exportPath, fmt.Sprintf("%s-%s", binaryName, s.InternalName)))
 	}
 
 	err = os.MkdirAll(exportPath, 0o755)
@@ -531,7 +852,23 @@ func (s *SubSystem) ExportBin(binary string, exportPath string) error {
 		return err
 	}
 
-	return nil
+	return s.updateExportEnv(joinedPath)
+}
+
+// addBinPath records the directory binaries are exported to, unless it
+// is the shared one, so that the exports can be found later.
+func (s *SubSystem) addBinPath(exportPath string) error {
+	exportPath, err := filepath.Abs(exportPath)
+	if err != nil {
+		return err
+	}
+
+	if exportPath == exportedBinDir() || slices.Contains(s.BinPaths, exportPath) {
+		return nil
+	}
+
+	s.BinPaths = append(s.BinPaths, exportPath)
+	return s.SaveMetadata()
 }
 
 func (s *SubSystem) UnexportDesktopEntry(app string) error {
@@ -545,12 +882,12 @@ func (s *SubSystem) UnexportDesktopEntry(app string) error {
 
 func (s *SubSystem) UnexportBin(binary string, exportPath string) error {
 	if !strings.HasPrefix(binary, "/") {
-		binaryPath, err := s.Exec(true, false, "which", binary)
+		binaryPath, err := s.Which(binary)
 		if err != nil {
 			return err
 		}
 
-		binary = strings.TrimSpace(binaryPath)
+		binary = binaryPath
 	}
 
 	dbox, err := NewDbox()
@@ -560,3 +897,496 @@ func (s *SubSystem) UnexportBin(binary string, exportPath string) error {
 
 	return dbox.ContainerUnexportBin(s.InternalName, binary, s.IsRootfull)
 }
+
+// ListUpgradable refreshes the package manager metadata and returns the
+// packages which can be upgraded in the subsystem.
+func (s *SubSystem) ListUpgradable() ([]UpgradablePackage, error) {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return nil, err
+	}
+
+	if !pkgManager.Supports(PkgManagerOpListUpgradable) {
+		return nil, &UnsupportedOperationError{PkgManager: pkgManager.Name, Operation: PkgManagerOpListUpgradable}
+	}
+
+	if pkgManager.Supports(PkgManagerOpUpdate) {
+		_, err = s.Exec(true, false, pkgManager.GenCmd(pkgManager.CmdUpdate)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := s.Exec(true, false, pkgManager.GenCmd(pkgManager.CmdListUpgradable)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseUpgradable(out), nil
+}
+
+// IsRunning reports whether the subsystem container is running, based on
+// the status reported by the container engine.
+func (s *SubSystem) IsRunning() bool {
+	status := strings.ToLower(s.Status)
+	return strings.HasPrefix(status, "up") || strings.HasPrefix(status, "running")
+}
+
+// Search runs the package manager search inside the subsystem and returns
+// the parsed results.
+func (s *SubSystem) Search(query ...string) ([]SearchResult, error) {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return nil, err
+	}
+
+	if !pkgManager.Supports(PkgManagerOpSearch) {
+		return nil, &UnsupportedOperationError{PkgManager: pkgManager.Name, Operation: PkgManagerOpSearch}
+	}
+
+	out, err := s.Exec(true, false, pkgManager.GenCmd(pkgManager.CmdSearch, query...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSearch(out), nil
+}
+
+// Which returns the path of the command inside the subsystem, failing
+// with ErrCommandNotFound when it is not available.
+func (s *SubSystem) Which(command string) (string, error) {
+	// command -v is part of POSIX sh, unlike which which is missing from
+	// minimal images
+	out, err := s.Exec(true, false, "sh", "-c", `command -v "$1"`, "sh", command)
+	if err != nil {
+		return "", err
+	}
+
+	// builtins, aliases and functions are printed without a path, while
+	// the shared bin directory holds the wrappers exported by every
+	// subsystem, which would resolve to another one
+	path := strings.TrimSpace(out)
+	if !filepath.IsAbs(path) || filepath.Dir(path) == exportedBinDir() {
+		return "", &NotFoundError{Err: ErrCommandNotFound, Name: command}
+	}
+
+	return path, nil
+}
+
+// Owner returns the name of the package owning the file inside the
+// subsystem.
+func (s *SubSystem) Owner(path string) (string, error) {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return "", err
+	}
+
+	if !pkgManager.Supports(PkgManagerOpOwns) {
+		return "", &UnsupportedOperationError{PkgManager: pkgManager.Name, Operation: PkgManagerOpOwns}
+	}
+
+	out, err := s.Exec(true, false, pkgManager.GenCmd(pkgManager.CmdOwns, path)...)
+	if err != nil {
+		return "", err
+	}
+
+	return ParseOwner(out), nil
+}
+
+// IsBinaryExported reports whether the binary is exported to the host by
+// the subsystem, including the copies suffixed with the internal name which
+// are created when another binary with the same name is already exported.
+func (s *SubSystem) IsBinaryExported(binary string) bool {
+	binaries := findExportedBinaries(s.InternalName)
+	_, ok := binaries[binary]
+	if !ok {
+		_, ok = binaries[fmt.Sprintf("%s-%s", binary, s.InternalName)]
+	}
+	return ok
+}
+
+// StackDiff represents the differences between the packages of a subsystem
+// and the ones of its stack. Missing are the stack packages which are not
+// installed, Extra the packages explicitly installed through apx which are
+// not part of the stack, their dependencies are never reported.
+type StackDiff struct {
+	Missing []string
+	Extra   []string
+}
+
+// DiffStack compares the packages installed in the subsystem with the ones
+// declared by its stack. The extra packages are found by replaying the
+// packages requested by the recorded transactions, so packages installed
+// directly with the package manager, or by transactions recorded before
+// the requested packages were, are not reported.
+func (s *SubSystem) DiffStack() (*StackDiff, error) {
+	installed, err := s.InstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := ListTransactions(s)
+	if err != nil {
+		return nil, err
+	}
+
+	installedSet := map[string]bool{}
+	for _, pkg := range installed {
+		installedSet[pkg] = true
+	}
+
+	stackSet := map[string]bool{}
+	diff := &StackDiff{Missing: []string{}, Extra: []string{}}
+	for _, pkg := range s.Stack.Packages {
+		stackSet[pkg] = true
+		if !installedSet[pkg] {
+			diff.Missing = append(diff.Missing, pkg)
+		}
+	}
+
+	for _, pkg := range requestedPackages(transactions) {
+		if installedSet[pkg] && !stackSet[pkg] {
+			diff.Extra = append(diff.Extra, pkg)
+		}
+	}
+
+	return diff, nil
+}
+
+// RebaseResult represents the outcome of a rebase. Packages, Apps and
+// Binaries are the user-installed packages and the exports which were
+// restored, the Failed ones could not be restored in the new container.
+type RebaseResult struct {
+	Packages       []string
+	FailedPackages []string
+	Apps           []string
+	FailedApps     []string
+	Binaries       []string
+	FailedBinaries []string
+}
+
+// Rebase recreates the subsystem from the given stack, which can be its
+// current one after its base image changed, keeping the same home. The
+// new container is created next to the current one, which is only removed
+// once the new one is ready, then the packages explicitly installed
+// through apx are installed again and the apps and binaries exported
+// again. Unless allowEmpty is set, subsystems without a recorded request
+// of their packages are refused with ErrNoTransactionHistory, since those
+// packages could not be restored.
+func (s *SubSystem) Rebase(stack *Stack, allowEmpty bool) (*RebaseResult, error) {
+	transactions, err := ListTransactions(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowEmpty && !slices.ContainsFunc(transactions, func(transaction Transaction) bool {
+		return len(transaction.Requested) > 0
+	}) {
+		return nil, ErrNoTransactionHistory
+	}
+
+	diff, err := s.DiffStack()
+	if err != nil {
+		return nil, err
+	}
+
+	apps := s.exportedApps()
+	binaries := s.resolvedExportedBinaries()
+
+	newPkgManager, err := stack.GetPkgManager()
+	if err != nil {
+		return nil, err
+	}
+
+	rebased := s.replacement()
+	rebased.Stack = stack
+
+	// the current container is left untouched until the new one works
+	err = rebased.create(stack.Base, stack.Packages)
+	if err == nil {
+		err = rebased.initialize()
+	}
+	if err != nil {
+		rebased.discard()
+		return nil, err
+	}
+
+	result := &RebaseResult{
+		Packages:       []string{},
+		FailedPackages: []string{},
+		Apps:           []string{},
+		FailedApps:     []string{},
+		Binaries:       []string{},
+		FailedBinaries: []string{},
+	}
+
+	// the packages are installed all at once first, then one by one to
+	// find out which ones are not available on the new base
+	packages := []string{}
+	for _, pkg := range diff.Extra {
+		if !slices.Contains(stack.Packages, pkg) {
+			packages = append(packages, pkg)
+		}
+	}
+
+	if len(packages) > 0 {
+		emitProgress(ProgressEvent{Subsystem: s.Name, Phase: ProgressInstalling, Message: strings.Join(packages, " ")})
+
+		// the output is captured, so the package manager cannot prompt
+		_, err = rebased.Exec(true, false, newPkgManager.genCmd(true, newPkgManager.CmdInstall, packages...)...)
+		if err == nil {
+			result.Packages = packages
+		} else {
+			for _, pkg := range packages {
+				_, err = rebased.Exec(true, false, newPkgManager.genCmd(true, newPkgManager.CmdInstall, pkg)...)
+				if err != nil {
+					result.FailedPackages = append(result.FailedPackages, pkg)
+					continue
+				}
+				result.Packages = append(result.Packages, pkg)
+			}
+		}
+	}
+
+	err = s.moveExportsTo(&rebased)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.replaceWith(&rebased)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, app := range apps {
+		err = s.ExportDesktopEntry(app)
+		if err != nil {
+			result.FailedApps = append(result.FailedApps, app)
+			continue
+		}
+		result.Apps = append(result.Apps, app)
+	}
+
+	// the binary wrappers were moved to the new container, so they keep
+	// working as long as the binary exists in it
+	for _, binary := range binaries {
+		if _, err := s.Which(binary); err != nil {
+			result.FailedBinaries = append(result.FailedBinaries, binary)
+			continue
+		}
+		result.Binaries = append(result.Binaries, binary)
+	}
+	s.removeDanglingBinaries()
+
+	progressDone(s.Name)
+	return result, nil
+}
+
+// exportedApps returns the names of the applications the subsystem exports,
+// as accepted by ExportDesktopEntry.
+func (s *SubSystem) exportedApps() []string {
+	apps := []string{}
+	for _, file := range s.exportedDesktopFiles() {
+		app := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), s.InternalName+"-"), ".desktop")
+		apps = append(apps, app)
+	}
+
+	return apps
+}
+
+// exportedDesktopFiles returns the paths of the desktop entries the
+// subsystem exports.
+func (s *SubSystem) exportedDesktopFiles() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return []string{}
+	}
+
+	files, err := filepath.Glob(fmt.Sprintf("%s/.local/share/applications/%s-*.desktop", home, s.InternalName))
+	if err != nil {
+		return []string{}
+	}
+
+	return files
+}
+
+// Clone creates a new subsystem with the given name from a snapshot of the
+// current state of the subsystem, including its packages.
+func (s *SubSystem) Clone(name string) (*SubSystem, error) {
+	clone, err := s.clone(name, genSubSystemID())
+	if err != nil {
+		return nil, err
+	}
+
+	err = copyTransactions(s, clone, false)
+	if err != nil {
+		clone.discard()
+		return nil, err
+	}
+
+	progressDone(clone.Name)
+	return clone, nil
+}
+
+func (s *SubSystem) clone(name string, id string) (*SubSystem, error) {
+	err := ValidateSubSystemName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dbox, err := NewDbox()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := findSubSystemContainer(dbox, name, s.IsRootfull); err == nil {
+		return nil, &ExistsError{Name: name}
+	}
+
+	internalName := genInternalName(name, id)
+
+	image := snapshotImageName(internalName)
+	err = dbox.ContainerCommit(s.InternalName, image, s.IsRootfull)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := *s
+	clone.ID = id
+	clone.Name = name
+	clone.InternalName = internalName
+	clone.Status = ""
+	clone.ExportedPrograms = nil
+
+	// the snapshot already contains the stack packages
+	err = clone.create(image, nil)
+	if err != nil {
+		clone.discard()
+		return nil, err
+	}
+
+	return &clone, nil
+}
+
+// Rename renames the subsystem by recreating its container with the new
+// name, then updates the exported apps and binaries to use it. The ID is
+// kept unless the new internal name would be the same as the current one.
+func (s *SubSystem) Rename(name string) error {
+	id := s.ID
+	if id == "" || genInternalName(name, id) == s.InternalName {
+		id = genSubSystemID()
+	}
+
+	renamed, err := s.clone(name, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.moveExportsTo(renamed)
+	if err != nil {
+		return err
+	}
+
+	// the subsystem is still complete, so the renamed one is dropped
+	err = s.Remove()
+	if err != nil {
+		_ = renamed.moveExports(s)
+		renamed.discard()
+		return err
+	}
+
+	err = s.RemoveMetadata()
+	if err != nil {
+		return err
+	}
+
+	err = copyTransactions(s, renamed, true)
+	if err != nil {
+		return err
+	}
+
+	*s = *renamed
+	progressDone(s.Name)
+	return nil
+}
+
+// moveExports rewrites the desktop entries and binary wrappers exported by
+// the subsystem so that they run in the target subsystem instead.
+func (s *SubSystem) moveExports(target *SubSystem) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	emitProgress(ProgressEvent{Subsystem: target.Name, Phase: ProgressExporting})
+
+	// the exports refer to the env file of the target from now on
+	if env := target.envList(); len(env) > 0 {
+		_, err = writeEnvFile(target.InternalName, env)
+		if err != nil {
+			return err
+		}
+	}
+
+	replacer := strings.NewReplacer(
+		s.InternalName, target.InternalName,
+		fmt.Sprintf(" on %s", s.Name), fmt.Sprintf(" on %s", target.Name),
+	)
+
+	desktopFiles, err := filepath.Glob(fmt.Sprintf("%s/.local/share/applications/%s-*.desktop", home, s.InternalName))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range desktopFiles {
+		newFile := filepath.Join(filepath.Dir(file), target.InternalName+strings.TrimPrefix(filepath.Base(file), s.InternalName))
+		err = rewriteExport(file, newFile, s.InternalName, replacer)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, path := range findExportedBinaryPaths(s.InternalName) {
+		newPath := path
+		if strings.HasSuffix(path, "-"+s.InternalName) {
+			newPath = strings.TrimSuffix(path, s.InternalName) + target.InternalName
+		}
+
+		err = rewriteExport(path, newPath, s.InternalName, replacer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rewriteExport applies the replacer to the exported file at path and moves
+// it to newPath, keeping its permissions. Files which do not enter the
+// owner container are left untouched, since the glob and header matching
+// also catch subsystems whose name starts with the one of the owner.
+func rewriteExport(path string, newPath string, owner string, replacer *strings.Replacer) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(string(data), "-n "+owner+" ") {
+		return nil
+	}
+
+	err = os.WriteFile(newPath, []byte(replacer.Replace(string(data))), info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if newPath == path {
+		return nil
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,80 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/vanilla-os/apx/v3/settings"
+)
+
+// setupTestApx points the home and the apx storage to temporary
+// directories for the duration of the test.
+func setupTestApx(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	storage := filepath.Join(home, ".local", "share", "apx")
+	err := os.MkdirAll(storage, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := apx
+	apx = &Apx{Cnf: &settings.Config{ApxStoragePath: storage}}
+	t.Cleanup(func() { apx = previous })
+
+	return home
+}
+
+func writeTestWrapper(t *testing.T, path string, internalName string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrapper := "#!/bin/sh\n# distrobox_binary\n# name: " + internalName + "\nexec distrobox enter -n " + internalName + " -- htop \"$@\"\n"
+	err = os.WriteFile(path, []byte(wrapper), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindExportedBinaryPaths(t *testing.T) {
+	home := setupTestApx(t)
+
+	custom := filepath.Join(home, "bin")
+	shared := filepath.Join(home, ".local", "bin", "htop")
+	exported := filepath.Join(custom, "htop")
+	writeTestWrapper(t, shared, "apx-test")
+	writeTestWrapper(t, exported, "apx-test")
+	writeTestWrapper(t, filepath.Join(home, ".local", "bin", "vim"), "apx-other")
+
+	s := &SubSystem{InternalName: "apx-test"}
+	err := s.addBinPath(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := findExportedBinaryPaths("apx-test")
+	slices.Sort(paths)
+	want := []string{exported, shared}
+	slices.Sort(want)
+	if !slices.Equal(paths, want) {
+		t.Errorf("findExportedBinaryPaths() = %q, want %q", paths, want)
+	}
+}
@@ -0,0 +1,513 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TransactionInstall = "install"
+	TransactionRemove  = "remove"
+	TransactionUpgrade = "upgrade"
+	TransactionUndo    = "undo"
+)
+
+// ErrNothingToUndo is returned by Undo when the subsystem has no recorded
+// transaction left to undo.
+var ErrNothingToUndo = errors.New("no transaction to undo")
+
+// ErrNoTransactionHistory is returned by Rebase when no transaction of the
+// subsystem records the packages requested by the user.
+var ErrNoTransactionHistory = errors.New("no transaction records the requested packages")
+
+// Transaction represents the packages actually changed by an install,
+// remove or upgrade in a subsystem, as found by comparing the installed packages
+// before and after the operation. Requested lists the packages the user
+// asked for, while Added and Removed also include their dependencies.
+// Binaries lists the exported binaries which were left without their
+// command by the transaction. SubsystemID is the ID of the subsystem, the
+// name being only informative, empty for the subsystems created before
+// they had one.
+type Transaction struct {
+	ID          string
+	Subsystem   string
+	SubsystemID string `json:",omitempty"`
+	Time        time.Time
+	Operation   string
+	Requested   []string `json:",omitempty"`
+	Added       []string
+	Removed     []string
+	Binaries    []string `json:",omitempty"`
+	Undoes      string   `json:",omitempty"`
+}
+
+// TransactionConflictError is returned by Undo when the packages changed by
+// the transaction were changed again afterwards, so reverting it would not
+// restore the previous state.
+type TransactionConflictError struct {
+	Transaction *Transaction
+
+	// Missing are the packages added by the transaction which are no
+	// longer installed.
+	Missing []string
+
+	// Reinstalled are the packages removed by the transaction which are
+	// installed again.
+	Reinstalled []string
+}
+
+func (e *TransactionConflictError) Error() string {
+	return fmt.Sprintf("transaction %s conflicts with later changes: missing %v, reinstalled %v", e.Transaction.ID, e.Missing, e.Reinstalled)
+}
+
+func transactionLogPath() string {
+	return filepath.Join(apx.Cnf.ApxStoragePath, "transactions.jsonl")
+}
+
+func logTransaction(transaction Transaction) error {
+	data, err := json.Marshal(transaction)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(transactionLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ownsTransaction reports whether the transaction was recorded for the
+// subsystem. Subsystems without an ID are matched by name, so a new
+// subsystem reusing the name of a removed one never gets its transactions.
+func (s *SubSystem) ownsTransaction(transaction Transaction) bool {
+	if s.ID == "" {
+		return transaction.SubsystemID == "" && transaction.Subsystem == s.Name
+	}
+	return transaction.SubsystemID == s.ID
+}
+
+// ListTransactions returns the transactions recorded for the subsystem,
+// oldest first. Malformed lines are skipped.
+func ListTransactions(subSystem *SubSystem) ([]Transaction, error) {
+	transactions := []Transaction{}
+
+	f, err := os.Open(transactionLogPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return transactions, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var transaction Transaction
+		if json.Unmarshal(scanner.Bytes(), &transaction) != nil {
+			continue
+		}
+
+		if subSystem.ownsTransaction(transaction) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions, scanner.Err()
+}
+
+// LastTransaction returns the most recent transaction of the subsystem
+// which has not been undone yet, or nil if there is none. Upgrades are
+// skipped, since the previous versions cannot be installed back.
+func LastTransaction(subSystem *SubSystem) (*Transaction, error) {
+	transactions, err := ListTransactions(subSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	undone := map[string]bool{}
+	for _, transaction := range transactions {
+		if transaction.Operation == TransactionUndo {
+			undone[transaction.Undoes] = true
+		}
+	}
+
+	for i := len(transactions) - 1; i >= 0; i-- {
+		transaction := transactions[i]
+		if transaction.Operation == TransactionUndo || transaction.Operation == TransactionUpgrade || undone[transaction.ID] {
+			continue
+		}
+		return &transaction, nil
+	}
+
+	return nil, nil
+}
+
+// InstalledPackages returns the names of the packages installed in the
+// subsystem, using the package manager CmdList.
+func (s *SubSystem) InstalledPackages() ([]string, error) {
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return nil, err
+	}
+
+	if !pkgManager.Supports(PkgManagerOpList) {
+		return nil, &UnsupportedOperationError{PkgManager: pkgManager.Name, Operation: PkgManagerOpList}
+	}
+
+	out, err := s.Exec(true, false, pkgManager.GenCmd(pkgManager.CmdList)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseInstalled(out), nil
+}
+
+// requestedPackages replays the packages requested by the transactions,
+// oldest first, and returns the ones which were installed and not removed
+// afterwards, in installation order. An undo reverts the request of the
+// transaction it undoes.
+func requestedPackages(transactions []Transaction) []string {
+	byID := map[string]Transaction{}
+	requested := map[string]bool{}
+	order := []string{}
+	for _, transaction := range transactions {
+		byID[transaction.ID] = transaction
+
+		packages := transaction.Requested
+		install := transaction.Operation == TransactionInstall
+		if transaction.Operation == TransactionUndo {
+			undone, ok := byID[transaction.Undoes]
+			if !ok {
+				continue
+			}
+			packages = undone.Requested
+			install = undone.Operation == TransactionRemove
+		}
+
+		for _, pkg := range packages {
+			if install && !requested[pkg] {
+				order = append(order, pkg)
+			}
+			requested[pkg] = install
+		}
+		for _, pkg := range transaction.Removed {
+			requested[pkg] = false
+		}
+	}
+
+	packages := []string{}
+	for _, pkg := range order {
+		if requested[pkg] {
+			packages = append(packages, pkg)
+			// a package removed and installed again is listed once
+			requested[pkg] = false
+		}
+	}
+	return packages
+}
+
+// RecordTransaction runs fn, an install or remove of the requested
+// packages or an upgrade, and records the packages it changed so that it can be undone
+// later. If the installed packages cannot be listed, fn is run without
+// recording anything.
+func (s *SubSystem) RecordTransaction(operation string, requested []string, fn func() error) error {
+	return s.recordTransaction(Transaction{Operation: operation, Requested: requested}, fn)
+}
+
+func (s *SubSystem) recordTransaction(transaction Transaction, fn func() error) error {
+	before, err := s.InstalledPackages()
+	if err != nil {
+		return fn()
+	}
+
+	binaries := s.resolvedExportedBinaries()
+
+	err = fn()
+	if err != nil {
+		return err
+	}
+
+	after, err := s.InstalledPackages()
+	if err != nil {
+		return err
+	}
+
+	transaction.Added, transaction.Removed = diffPackages(before, after)
+	// undos are always recorded, so that the transaction they revert is
+	// not offered again
+	if len(transaction.Added) == 0 && len(transaction.Removed) == 0 && transaction.Operation != TransactionUndo {
+		return nil
+	}
+
+	if len(transaction.Removed) > 0 {
+		for _, binary := range binaries {
+			if _, err := s.Which(binary); err != nil {
+				transaction.Binaries = append(transaction.Binaries, binary)
+			}
+		}
+	}
+
+	transaction.ID = uuid.New().String()
+	transaction.Subsystem = s.Name
+	transaction.SubsystemID = s.ID
+	transaction.Time = time.Now()
+	return logTransaction(transaction)
+}
+
+// Undo reverts the last transaction of the subsystem, removing the packages
+// it installed and installing the packages it removed, along with their
+// exports. It returns the reverted transaction, ErrNothingToUndo if there
+// is none, or a *TransactionConflictError if the packages changed since.
+func (s *SubSystem) Undo() (*Transaction, error) {
+	transaction, err := LastTransaction(s)
+	if err != nil {
+		return nil, err
+	}
+	if transaction == nil {
+		return nil, ErrNothingToUndo
+	}
+
+	err = s.CheckUndo(transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgManager, err := s.Stack.GetPkgManager()
+	if err != nil {
+		return nil, err
+	}
+
+	undo := Transaction{Operation: TransactionUndo, Undoes: transaction.ID}
+	err = s.recordTransaction(undo, func() error {
+		if len(transaction.Added) > 0 {
+			if !pkgManager.Supports(PkgManagerOpRemove) {
+				return &UnsupportedOperationError{PkgManager: pkgManager.Name, Operation: PkgManagerOpRemove}
+			}
+
+			for _, app := range s.appsWithDesktopEntry(transaction.Added) {
+				_ = s.UnexportDesktopEntry(app)
+			}
+
+			_, err := s.Exec(false, false, pkgManager.GenCmd(pkgManager.CmdRemove, transaction.Added...)...)
+			if err != nil {
+				return err
+			}
+
+			s.removeDanglingBinaries()
+		}
+
+		if len(transaction.Removed) > 0 {
+			if !pkgManager.Supports(PkgManagerOpInstall) {
+				return &UnsupportedOperationError{PkgManager: pkgManager.Name, Operation: PkgManagerOpInstall}
+			}
+
+			_, err := s.Exec(false, false, pkgManager.GenCmd(pkgManager.CmdInstall, transaction.Removed...)...)
+			if err != nil {
+				return err
+			}
+
+			// not every package ships a desktop entry
+			for _, app := range transaction.Removed {
+				_ = s.ExportDesktopEntry(app)
+			}
+
+			for _, binary := range transaction.Binaries {
+				if !s.IsBinaryExported(binary) {
+					_ = s.ExportBin(binary, "")
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// CheckUndo verifies the packages changed by the transaction are still in
+// the state it left them in.
+func (s *SubSystem) CheckUndo(transaction *Transaction) error {
+	installed, err := s.InstalledPackages()
+	if err != nil {
+		return err
+	}
+
+	installedSet := map[string]bool{}
+	for _, pkg := range installed {
+		installedSet[pkg] = true
+	}
+
+	conflict := &TransactionConflictError{Transaction: transaction}
+	for _, pkg := range transaction.Added {
+		if !installedSet[pkg] {
+			conflict.Missing = append(conflict.Missing, pkg)
+		}
+	}
+	for _, pkg := range transaction.Removed {
+		if installedSet[pkg] {
+			conflict.Reinstalled = append(conflict.Reinstalled, pkg)
+		}
+	}
+
+	if len(conflict.Missing) > 0 || len(conflict.Reinstalled) > 0 {
+		return conflict
+	}
+
+	return nil
+}
+
+// resolvedExportedBinaries returns the commands behind the binaries the
+// subsystem exports, without the internal name suffix added to duplicates.
+func (s *SubSystem) resolvedExportedBinaries() []string {
+	binaries := []string{}
+	for name := range findExportedBinaries(s.InternalName) {
+		binaries = append(binaries, strings.TrimSuffix(name, "-"+s.InternalName))
+	}
+	return binaries
+}
+
+// removeDanglingBinaries removes the exported binaries whose command is no
+// longer available in the subsystem.
+func (s *SubSystem) removeDanglingBinaries() {
+	for _, path := range findExportedBinaryPaths(s.InternalName) {
+		if _, err := s.Which(strings.TrimSuffix(filepath.Base(path), "-"+s.InternalName)); err == nil {
+			continue
+		}
+		_ = os.Remove(path)
+	}
+}
+
+// appsWithDesktopEntry returns the packages having a desktop entry
+// exported by the subsystem, matched by name as distrobox does.
+func (s *SubSystem) appsWithDesktopEntry(packages []string) []string {
+	exported := s.exportedApps()
+	apps := []string{}
+	for _, pkg := range packages {
+		for _, app := range exported {
+			if strings.Contains(strings.ToLower(app), strings.ToLower(pkg)) {
+				apps = append(apps, pkg)
+				break
+			}
+		}
+	}
+	return apps
+}
+
+// diffPackages returns the packages only in after and only in before.
+func diffPackages(before []string, after []string) ([]string, []string) {
+	beforeSet := map[string]bool{}
+	for _, pkg := range before {
+		beforeSet[pkg] = true
+	}
+
+	afterSet := map[string]bool{}
+	added := []string{}
+	for _, pkg := range after {
+		if afterSet[pkg] {
+			continue
+		}
+		afterSet[pkg] = true
+		if !beforeSet[pkg] {
+			added = append(added, pkg)
+		}
+	}
+
+	removed := []string{}
+	for _, pkg := range before {
+		if !afterSet[pkg] {
+			removed = append(removed, pkg)
+			afterSet[pkg] = true
+		}
+	}
+
+	return added, removed
+}
+
+// copyTransactions records the transactions of the subsystem from as ones
+// of the subsystem to as well. If move is true, they are no longer
+// associated with from.
+func copyTransactions(from *SubSystem, to *SubSystem, move bool) error {
+	return from.rewriteTransactions(func(transaction Transaction) []Transaction {
+		copied := transaction
+		copied.Subsystem = to.Name
+		copied.SubsystemID = to.ID
+		if move {
+			return []Transaction{copied}
+		}
+		return []Transaction{transaction, copied}
+	})
+}
+
+// ForgetTransactions removes the transactions recorded for the subsystem,
+// once its packages are gone.
+func (s *SubSystem) ForgetTransactions() error {
+	return s.rewriteTransactions(func(Transaction) []Transaction {
+		return nil
+	})
+}
+
+// rewriteTransactions rewrites the transaction log, replacing each of the
+// transactions of the subsystem with the ones returned by fn.
+func (s *SubSystem) rewriteTransactions(fn func(Transaction) []Transaction) error {
+	data, err := os.ReadFile(transactionLogPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var out []byte
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var transaction Transaction
+		if json.Unmarshal([]byte(line), &transaction) != nil || !s.ownsTransaction(transaction) {
+			out = append(out, line+"\n"...)
+			continue
+		}
+
+		for _, rewritten := range fn(transaction) {
+			data, err := json.Marshal(rewritten)
+			if err != nil {
+				return err
+			}
+			out = append(out, append(data, '\n')...)
+		}
+	}
+
+	tmpPath := transactionLogPath() + ".tmp"
+	err = os.WriteFile(tmpPath, out, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, transactionLogPath())
+}
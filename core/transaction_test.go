@@ -0,0 +1,207 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequestedPackages(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		want         []string
+	}{
+		{
+			"dependencies are not requested",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Requested: []string{"htop"}, Added: []string{"htop", "libncursesw6"}},
+			},
+			[]string{"htop"},
+		},
+		{
+			"removed",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Requested: []string{"htop", "vim"}, Added: []string{"htop", "vim", "vim-common"}},
+				{ID: "2", Operation: TransactionRemove, Requested: []string{"vim"}, Removed: []string{"vim"}},
+			},
+			[]string{"htop"},
+		},
+		{
+			"removed as a dependency",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Requested: []string{"libfoo"}, Added: []string{"libfoo"}},
+				{ID: "2", Operation: TransactionRemove, Requested: []string{"foo-utils"}, Removed: []string{"foo-utils", "libfoo"}},
+			},
+			[]string{},
+		},
+		{
+			"removed by an upgrade",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Requested: []string{"htop", "python3-six"}, Added: []string{"htop", "python3-six"}},
+				{ID: "2", Operation: TransactionUpgrade, Added: []string{"libnl-3-200"}, Removed: []string{"python3-six"}},
+			},
+			[]string{"htop"},
+		},
+		{
+			"undone install",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Requested: []string{"htop"}, Added: []string{"htop"}},
+				{ID: "2", Operation: TransactionUndo, Undoes: "1", Removed: []string{"htop"}},
+			},
+			[]string{},
+		},
+		{
+			"undone remove",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Requested: []string{"htop"}, Added: []string{"htop"}},
+				{ID: "2", Operation: TransactionRemove, Requested: []string{"htop"}, Removed: []string{"htop"}},
+				{ID: "3", Operation: TransactionUndo, Undoes: "2", Added: []string{"htop"}},
+			},
+			[]string{"htop"},
+		},
+		{
+			"reinstalled",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Requested: []string{"htop"}, Added: []string{"htop"}},
+				{ID: "2", Operation: TransactionRemove, Requested: []string{"htop"}, Removed: []string{"htop"}},
+				{ID: "3", Operation: TransactionInstall, Requested: []string{"htop"}, Added: []string{"htop"}},
+			},
+			[]string{"htop"},
+		},
+		{
+			"recorded without requested packages",
+			[]Transaction{
+				{ID: "1", Operation: TransactionInstall, Added: []string{"htop", "libncursesw6"}},
+			},
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requestedPackages(tt.transactions)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("requestedPackages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyTransactions(t *testing.T) {
+	tests := []struct {
+		name     string
+		move     bool
+		wantFrom int
+	}{
+		{"copy", false, 2},
+		{"move", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestApx(t)
+
+			dev := &SubSystem{ID: "d1", Name: "dev"}
+			devCopy := &SubSystem{ID: "c1", Name: "dev-copy"}
+			other := &SubSystem{ID: "o1", Name: "other"}
+
+			for _, transaction := range []Transaction{
+				{ID: "1", Subsystem: "dev", SubsystemID: "d1", Operation: TransactionInstall, Requested: []string{"htop"}, Added: []string{"htop"}},
+				{ID: "2", Subsystem: "other", SubsystemID: "o1", Operation: TransactionInstall, Added: []string{"vim"}},
+				{ID: "3", Subsystem: "dev", SubsystemID: "d1", Operation: TransactionRemove, Requested: []string{"htop"}, Removed: []string{"htop"}},
+			} {
+				err := logTransaction(transaction)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := copyTransactions(dev, devCopy, tt.move)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			from, err := ListTransactions(dev)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(from) != tt.wantFrom {
+				t.Errorf("got %d transactions for the source, want %d", len(from), tt.wantFrom)
+			}
+
+			to, err := ListTransactions(devCopy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(to) != 2 || to[0].ID != "1" || to[1].ID != "3" {
+				t.Fatalf("got transactions %+v for the target, want 1 and 3", to)
+			}
+			if !reflect.DeepEqual(to[0].Requested, []string{"htop"}) {
+				t.Errorf("got requested packages %q, want [htop]", to[0].Requested)
+			}
+
+			otherTransactions, err := ListTransactions(other)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(otherTransactions) != 1 {
+				t.Errorf("got %d transactions for an unrelated subsystem, want 1", len(otherTransactions))
+			}
+		})
+	}
+}
+
+func TestForgetTransactions(t *testing.T) {
+	setupTestApx(t)
+
+	legacy := &SubSystem{Name: "dev"}
+	for _, transaction := range []Transaction{
+		{ID: "1", Subsystem: "dev", Operation: TransactionInstall, Added: []string{"htop"}},
+		{ID: "2", Subsystem: "dev", SubsystemID: "d1", Operation: TransactionInstall, Added: []string{"vim"}},
+	} {
+		err := logTransaction(transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a subsystem reusing the name does not get the transactions
+	reused := &SubSystem{ID: "d2", Name: "dev"}
+	transactions, err := ListTransactions(reused)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("got transactions %+v for a subsystem reusing the name", transactions)
+	}
+
+	err = legacy.ForgetTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transactions, err = ListTransactions(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 0 {
+		t.Errorf("got transactions %+v after forgetting them", transactions)
+	}
+
+	transactions, err = ListTransactions(&SubSystem{ID: "d1", Name: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 1 {
+		t.Errorf("got %d transactions for the other subsystem, want 1", len(transactions))
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"unicode"
 )
 
 var ProcessPath string
@@ -65,6 +67,63 @@ func CopyToUserTemp(path string) (string, error) {
 	return newPath, nil
 }
 
+// localPackageExtensions lists the package file extensions recognised when
+// installing packages from the host.
+var localPackageExtensions = []string{
+	".deb",
+	".rpm",
+	".apk",
+	".pkg.tar.zst",
+	".pkg.tar.xz",
+	".pkg.tar.gz",
+	".pkg.tar",
+}
+
+// IsLocalPackage reports whether arg refers to a package file on the host,
+// i.e. it is an existing regular file given either as a path or with a
+// known package extension.
+func IsLocalPackage(arg string) bool {
+	if !strings.ContainsRune(arg, filepath.Separator) && trimPackageExtension(arg) == arg {
+		return false
+	}
+
+	info, err := os.Stat(arg)
+	if err != nil {
+		return false
+	}
+
+	return info.Mode().IsRegular()
+}
+
+// LocalPackageName guesses the package name from a package file name, e.g.
+// "foo_1.0-1_amd64.deb", "foo-1.0-1.x86_64.rpm" and
+// "foo-1.0-1-x86_64.pkg.tar.zst" all result in "foo".
+func LocalPackageName(path string) string {
+	name := trimPackageExtension(filepath.Base(path))
+
+	if strings.HasSuffix(filepath.Base(path), ".deb") {
+		return strings.Split(name, "_")[0]
+	}
+
+	parts := strings.Split(name, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" && unicode.IsDigit(rune(parts[i][0])) {
+			return strings.Join(parts[:i], "-")
+		}
+	}
+
+	return name
+}
+
+func trimPackageExtension(name string) string {
+	for _, ext := range localPackageExtensions {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
 func CopyFile(src, dst string) error {
 	source, err := os.Open(src)
 	if err != nil {
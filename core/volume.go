@@ -0,0 +1,139 @@
+package core
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Volume represents a host path or a named volume mounted in a subsystem.
+// Named volumes are managed by the container engine and can be shared
+// between subsystems, e.g. for package caches. Relabel lets SELinux
+// systems share the content with the container.
+type Volume struct {
+	Source      string
+	Destination string
+	ReadOnly    bool `yaml:",omitempty" json:",omitempty"`
+	Relabel     bool `yaml:",omitempty" json:",omitempty"`
+}
+
+var namedVolumeRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// IsNamed reports whether the source is a named volume instead of a host
+// path.
+func (v Volume) IsNamed() bool {
+	return !strings.HasPrefix(v.Source, "/")
+}
+
+// Validate checks the volume can be passed to the container engine.
+func (v Volume) Validate() error {
+	for _, path := range []string{v.Source, v.Destination} {
+		if path == "" || strings.ContainsAny(path, ":, \t\n") {
+			return &InvalidArgumentError{Argument: "volume path", Value: path}
+		}
+	}
+
+	if !filepath.IsAbs(v.Destination) {
+		return &InvalidArgumentError{Argument: "volume destination", Value: v.Destination, Reason: "it must be an absolute path"}
+	}
+
+	if v.IsNamed() && !namedVolumeRegex.MatchString(v.Source) {
+		return &InvalidArgumentError{Argument: "volume name", Value: v.Source}
+	}
+
+	return nil
+}
+
+// Spec returns the volume in the source:destination[:options] format
+// accepted by distrobox and the container engines.
+func (v Volume) Spec() string {
+	options := []string{}
+	if v.ReadOnly {
+		options = append(options, "ro")
+	}
+	if v.Relabel {
+		options = append(options, "z")
+	}
+
+	spec := fmt.Sprintf("%s:%s", v.Source, v.Destination)
+	if len(options) > 0 {
+		spec += ":" + strings.Join(options, ",")
+	}
+
+	return spec
+}
+
+// AllVolumes returns the volumes of the stack followed by the ones of the
+// subsystem.
+func (s *SubSystem) AllVolumes() []Volume {
+	volumes := []Volume{}
+	if s.Stack != nil {
+		volumes = append(volumes, s.Stack.Volumes...)
+	}
+	return append(volumes, s.Volumes...)
+}
+
+func (s *SubSystem) volumeArgs() []string {
+	args := []string{}
+	for _, volume := range s.AllVolumes() {
+		args = append(args, "--volume", volume.Spec())
+	}
+	return args
+}
+
+// AddVolume mounts the volume in the subsystem, recreating its container
+// from a snapshot of its current state.
+func (s *SubSystem) AddVolume(volume Volume) error {
+	err := volume.Validate()
+	if err != nil {
+		return err
+	}
+
+	if !volume.IsNamed() {
+		if _, err := os.Stat(volume.Source); err != nil {
+			return err
+		}
+	}
+
+	for _, existing := range s.AllVolumes() {
+		if existing.Destination == volume.Destination {
+			return &InvalidArgumentError{Argument: "volume destination", Value: volume.Destination, Reason: "a volume is already mounted there"}
+		}
+	}
+
+	s.Volumes = append(s.Volumes, volume)
+	return s.Recreate()
+}
+
+// RemoveVolume unmounts the volume mounted at destination, recreating the
+// container from a snapshot of its current state. Volumes defined by the
+// stack cannot be removed. The content of named volumes is kept.
+func (s *SubSystem) RemoveVolume(destination string) error {
+	for i, volume := range s.Volumes {
+		if volume.Destination == destination {
+			s.Volumes = append(s.Volumes[:i], s.Volumes[i+1:]...)
+			return s.Recreate()
+		}
+	}
+
+	if s.Stack != nil {
+		for _, volume := range s.Stack.Volumes {
+			if volume.Destination == destination {
+				return &InvalidArgumentError{Argument: "volume destination", Value: destination, Reason: fmt.Sprintf("the volume is defined by the stack %s", s.Stack.Name)}
+			}
+		}
+	}
+
+	return &NotFoundError{Err: ErrVolumeNotFound, Name: destination}
+}
@@ -0,0 +1,185 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+func (c *AutoUpdateEnableCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if c.Subsystem != "" && c.All {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("autoupdate.error.subsystemAndAll"))
+	}
+
+	if c.Schedule == "" {
+		c.Schedule = "daily"
+	}
+
+	err = core.ValidateSchedule(c.Schedule)
+	if err != nil {
+		return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("autoupdate.error.invalidSchedule"), c.Schedule))
+	}
+
+	if c.Subsystem != "" {
+		_, err := core.LoadSubSystem(c.Subsystem, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = core.EnableAutoUpdate(c.Schedule, c.Subsystem)
+	if err != nil {
+		return wrapError(err, "autoupdate.error.enabling")
+	}
+
+	target := c.Subsystem
+	if target == "" {
+		target = Apx.LC.Get("autoupdate.labels.allSubsystems")
+	}
+
+	Apx.Log.Infof(Apx.LC.Get("autoupdate.enable.info.success"), target, c.Schedule)
+	return nil
+}
+
+func (c *AutoUpdateDisableCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	err = core.DisableAutoUpdate()
+	if err != nil {
+		return wrapError(err, "autoupdate.error.disabling")
+	}
+
+	Apx.Log.Info(Apx.LC.Get("autoupdate.disable.info.success"))
+	return nil
+}
+
+func (c *AutoUpdateStatusCmd) Run() error {
+	unitsDir, err := core.AutoUpdateUnitsDir()
+	if err != nil {
+		return err
+	}
+
+	results, err := core.LastAutoUpdateResults()
+	if err != nil {
+		return err
+	}
+
+	enabled := core.AutoUpdateUnitsInstalled(unitsDir)
+
+	if c.Json || structuredOutput() {
+		status := struct {
+			Enabled bool
+			Results map[string]core.AutoUpdateResult
+		}{
+			Enabled: enabled,
+			Results: results,
+		}
+
+		return printStructured(status)
+	}
+
+	if enabled {
+		Apx.Log.Info(Apx.LC.Get("autoupdate.status.info.enabled"))
+	} else {
+		Apx.Log.Info(Apx.LC.Get("autoupdate.status.info.disabled"))
+	}
+
+	if len(results) == 0 {
+		Apx.Log.Info(Apx.LC.Get("autoupdate.status.info.noRuns"))
+		return nil
+	}
+
+	headers := []string{Apx.LC.Get("subsystems.labels.name"), Apx.LC.Get("autoupdate.labels.lastRun"), Apx.LC.Get("autoupdate.labels.result")}
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var data [][]string
+	for _, name := range names {
+		result := results[name]
+		outcome := Apx.LC.Get("autoupdate.labels.success")
+		if !result.Success {
+			outcome = result.Error
+		}
+		data = append(data, []string{result.Subsystem, result.Time.Format(time.DateTime), outcome})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+func (c *AutoUpdateRunCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if c.Subsystem != "" && c.All {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("autoupdate.error.subsystemAndAll"))
+	}
+
+	var subSystems []*core.SubSystem
+	if c.Subsystem != "" {
+		subSystem, err := core.LoadSubSystem(c.Subsystem, false)
+		if err != nil {
+			return err
+		}
+		subSystems = append(subSystems, subSystem)
+	} else {
+		var err error
+		subSystems, err = core.ListSubSystems(false, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, subSystem := range subSystems {
+		Apx.Log.Infof(Apx.LC.Get("autoupdate.run.info.updating"), subSystem.Name)
+
+		result := core.AutoUpdateResult{
+			Subsystem: subSystem.Name,
+			Time:      time.Now(),
+			Success:   true,
+		}
+
+		err := withHistory(subSystemHistory(subSystem), subSystem.AutoUpdate)
+		if err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			failed++
+			Apx.Log.Errorf(Apx.LC.Get("autoupdate.run.error.updating"), subSystem.Name, err)
+		}
+
+		err = core.LogAutoUpdateResult(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return newCommandError(errCodeUnknown, fmt.Sprintf(Apx.LC.Get("autoupdate.run.error.failed"), failed))
+	}
+
+	return nil
+}
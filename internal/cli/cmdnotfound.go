@@ -0,0 +1,117 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+func (c *CommandNotFoundCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if c.Shell != "" {
+		hook, err := core.GenCommandNotFoundHook(c.Shell)
+		if err != nil {
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("commandNotFound.error.unsupportedShell"), c.Shell))
+		}
+
+		fmt.Print(hook)
+		return nil
+	}
+
+	if len(c.Args) != 1 || c.Args[0] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("which.error.noCommand"))
+	}
+	command := c.Args[0]
+
+	suggestions, err := core.LookupCommand(command, !c.NoCache, c.Search)
+	if err != nil {
+		return err
+	}
+
+	if c.Json || structuredOutput() {
+		return printStructured(suggestions)
+	}
+
+	fmt.Fprintf(os.Stderr, Apx.LC.Get("commandNotFound.info.notFound")+"\n", command)
+	if len(suggestions) == 0 {
+		if !c.Search {
+			fmt.Fprintf(os.Stderr, Apx.LC.Get("commandNotFound.info.trySearch")+"\n", command)
+		}
+		return nil
+	}
+
+	fmt.Fprintln(os.Stderr, Apx.LC.Get("commandNotFound.info.suggestions"))
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(os.Stderr, "  %s\n", suggestion.Command(command))
+	}
+
+	// only offer to run the suggestion when there is no ambiguity
+	if len(suggestions) > 1 {
+		return nil
+	}
+
+	confirm, err := Apx.CLI.ConfirmAction(
+		fmt.Sprintf(Apx.LC.Get("commandNotFound.info.askRun"), suggestions[0].Command(command)),
+		"y", "N",
+		false,
+	)
+	if err != nil || !confirm {
+		return err
+	}
+
+	return applyCommandSuggestion(command, suggestions[0])
+}
+
+// applyCommandSuggestion installs the package or exports the binary in the
+// suggested subsystem.
+func applyCommandSuggestion(command string, suggestion core.CommandSuggestion) error {
+	subSystem, err := core.LoadSubSystem(suggestion.Subsystem, false)
+	if err != nil {
+		return err
+	}
+
+	if suggestion.Action == core.CommandSuggestionInstall {
+		pkgManager, err := subSystem.Stack.GetPkgManager()
+		if err != nil {
+			return wrapError(err, "runtimeCommand.error.cantAccessPkgManager")
+		}
+
+		cmdStr, err := pkgManagerCommands(pkgManager, core.PkgManagerOpInstall)
+		if err != nil {
+			return err
+		}
+
+		err = withHistory(subSystemHistory(subSystem), func() error {
+			return subSystem.RecordTransaction(core.TransactionInstall, []string{suggestion.Package}, func() error {
+				_, err := subSystem.Exec(false, false, pkgManager.GenCmd(cmdStr, suggestion.Package)...)
+				return err
+			})
+		})
+		if err != nil {
+			return wrapError(err, "runtimeCommand.error.executingCommand")
+		}
+	}
+
+	err = subSystem.ExportBin(command, "")
+	if err != nil {
+		return wrapError(err, "runtimeCommand.error.exportingBin")
+	}
+
+	_ = core.ClearCommandNotFoundCache()
+	Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.exportedBin"), command)
+	return nil
+}
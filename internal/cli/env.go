@@ -0,0 +1,117 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+func (c *SubsystemEnvListCmd) Run() error {
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	env := subSystem.AllEnv()
+
+	if c.Json || structuredOutput() {
+		return printStructured(env)
+	}
+
+	if len(env) == 0 {
+		Apx.Log.Infof(Apx.LC.Get("env.info.noVariables"), subSystem.Name)
+		return nil
+	}
+
+	headers := []string{
+		Apx.LC.Get("env.labels.name"),
+		Apx.LC.Get("env.labels.value"),
+		Apx.LC.Get("mounts.labels.origin"),
+	}
+	var data [][]string
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		origin := Apx.LC.Get("mounts.labels.stack")
+		if _, ok := subSystem.Env[key]; ok {
+			origin = Apx.LC.Get("mounts.labels.subsystem")
+		}
+		data = append(data, []string{key, env[key], origin})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+func (c *SubsystemEnvSetCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) == 0 {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("env.set.error.noVariables"))
+	}
+
+	env := map[string]string{}
+	for _, arg := range c.Args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("env.set.error.invalidFormat"), arg))
+		}
+
+		err := core.ValidateEnv(key, value)
+		if err != nil {
+			return wrapError(err, "env.error.invalid")
+		}
+		env[key] = value
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.SetEnv(env)
+	})
+	if err != nil {
+		return wrapError(err, "env.error.updating")
+	}
+
+	return printResult(subSystem.AllEnv(), fmt.Sprintf(Apx.LC.Get("env.info.updated"), subSystem.Name))
+}
+
+func (c *SubsystemEnvUnsetCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) == 0 {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("env.unset.error.noNames"))
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.UnsetEnv(c.Args)
+	})
+	if err != nil {
+		return wrapError(err, "env.error.updating")
+	}
+
+	return printResult(subSystem.AllEnv(), fmt.Sprintf(Apx.LC.Get("env.info.updated"), subSystem.Name))
+}
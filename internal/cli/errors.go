@@ -0,0 +1,207 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vanilla-os/apx/v3/core"
+	"github.com/vanilla-os/apx/v3/settings"
+)
+
+// Stable error codes, printed with the structured output and mapped to
+// the exit codes in errExitCodes.
+const (
+	errCodeUnknown         = "unknown"
+	errCodeInvalidArgument = "invalid_argument"
+	errCodeNotFound        = "not_found"
+	errCodeAlreadyExists   = "already_exists"
+	errCodeInUse           = "in_use"
+	errCodeUnsupported     = "unsupported"
+	errCodeUnavailable     = "unavailable"
+	errCodeConflict        = "conflict"
+	errCodeReadOnly        = "read_only"
+	errCodeOutdated        = "outdated"
+)
+
+// errExitCodes are the exit codes documented for each error code. Changing
+// them breaks the scripts relying on apx.
+var errExitCodes = map[string]int{
+	errCodeUnknown:         1,
+	errCodeInvalidArgument: 2,
+	errCodeNotFound:        3,
+	errCodeAlreadyExists:   4,
+	errCodeInUse:           5,
+	errCodeUnsupported:     6,
+	errCodeUnavailable:     7,
+	errCodeConflict:        8,
+	errCodeReadOnly:        126,
+	errCodeOutdated:        OutdatedExitCode,
+}
+
+// CommandError is an error returned by a command, carrying a stable code
+// along with the localized message. An empty message only sets the exit
+// code, for commands which already reported the outcome. Output is the
+// output of a failed command, captured with a structured output. Err is
+// the error which caused it, if any.
+type CommandError struct {
+	Code    string
+	Message string
+	Output  string `json:",omitempty"`
+	Err     error  `json:"-"`
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+func newCommandError(code string, message string) error {
+	return &CommandError{Code: code, Message: message}
+}
+
+// wrapError prefixes err with the localized message of key, which takes
+// args followed by err. The code and the output of err are kept, so is err
+// itself for errors.Is and errors.As.
+func wrapError(err error, key string, args ...any) error {
+	cause := commandErrorFrom(err)
+	return &CommandError{
+		Code:    cause.Code,
+		Message: fmt.Sprintf(Apx.LC.Get(key), append(args, cause.Message)...),
+		Output:  cause.Output,
+		Err:     err,
+	}
+}
+
+// commandErrorFrom converts err to a CommandError, translating the errors
+// returned by core.
+func commandErrorFrom(err error) *CommandError {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr
+	}
+
+	// the failed step is reported along with the error which caused it
+	var recreate *core.RecreateError
+	if errors.As(err, &recreate) {
+		cause := commandErrorFrom(recreate.Err)
+		step := Apx.LC.Get("apx.errors.recreateStep." + recreate.Step)
+		return &CommandError{Code: cause.Code, Message: fmt.Sprintf(Apx.LC.Get("apx.errors.recreateFailed"), step, cause.Message)}
+	}
+
+	var notFound *core.NotFoundError
+	if errors.As(err, &notFound) {
+		key := ""
+		switch notFound.Err {
+		case core.ErrSubsystemNotFound:
+			key = "apx.errors.subsystemNotFound"
+		case core.ErrStackNotFound:
+			key = "apx.errors.stackNotFound"
+		case core.ErrPkgManagerNotFound:
+			key = "apx.errors.pkgManagerNotFound"
+		case core.ErrContainerNotFound:
+			key = "apx.errors.containerNotFound"
+		case core.ErrImageNotFound:
+			key = "apx.errors.imageNotFound"
+		case core.ErrVolumeNotFound:
+			key = "apx.errors.volumeNotFound"
+		case core.ErrEnvNotFound:
+			key = "apx.errors.envNotFound"
+		}
+		if key != "" {
+			return &CommandError{Code: errCodeNotFound, Message: fmt.Sprintf(Apx.LC.Get(key), notFound.Name)}
+		}
+	}
+
+	var exists *core.ExistsError
+	if errors.As(err, &exists) {
+		return &CommandError{Code: errCodeAlreadyExists, Message: fmt.Sprintf(Apx.LC.Get("subsystems.new.error.alreadyExists"), exists.Name)}
+	}
+
+	var invalidName *core.InvalidNameError
+	if errors.As(err, &invalidName) {
+		return &CommandError{Code: errCodeInvalidArgument, Message: fmt.Sprintf(Apx.LC.Get("apx.errors.invalidSubsystemName"), invalidName.Name, invalidName.Reason)}
+	}
+
+	var invalidArgument *core.InvalidArgumentError
+	if errors.As(err, &invalidArgument) {
+		return &CommandError{Code: errCodeInvalidArgument, Message: invalidArgument.Error()}
+	}
+
+	var invalidStack *core.InvalidStackError
+	if errors.As(err, &invalidStack) {
+		if invalidStack.Field != "" {
+			return &CommandError{Code: errCodeInvalidArgument, Message: fmt.Sprintf(Apx.LC.Get("apx.errors.stackMissingField"), invalidStack.Path, invalidStack.Field)}
+		}
+		return &CommandError{Code: errCodeInvalidArgument, Message: fmt.Sprintf(Apx.LC.Get("apx.errors.stackInvalid"), invalidStack.Path, invalidStack.Err)}
+	}
+
+	var unsupported *core.UnsupportedOperationError
+	if errors.As(err, &unsupported) {
+		return &CommandError{Code: errCodeUnsupported, Message: fmt.Sprintf(Apx.LC.Get("runtimeCommand.error.unsupportedCommand"), unsupported.PkgManager, unsupported.Operation)}
+	}
+
+	switch {
+	case errors.Is(err, core.ErrImageNotFound):
+		return &CommandError{Code: errCodeNotFound, Message: err.Error()}
+	case errors.Is(err, core.ErrStackBuiltIn):
+		return &CommandError{Code: errCodeReadOnly, Message: Apx.LC.Get("stacks.update.error.builtIn")}
+	case errors.Is(err, core.ErrPkgManagerBuiltIn):
+		return &CommandError{Code: errCodeReadOnly, Message: Apx.LC.Get("pkgmanagers.update.error.builtIn")}
+	case errors.Is(err, core.ErrDistroboxNotFound):
+		return &CommandError{Code: errCodeUnavailable, Message: Apx.LC.Get("apx.errors.distroboxNotFound")}
+	case errors.Is(err, core.ErrEngineNotFound):
+		return &CommandError{Code: errCodeUnavailable, Message: Apx.LC.Get("apx.errors.engineNotFound")}
+	case errors.Is(err, settings.ErrInvalidPullPolicy):
+		return &CommandError{Code: errCodeInvalidArgument, Message: err.Error()}
+	}
+
+	// the output of a failed command is lost otherwise when captured
+	cmdErr = &CommandError{Code: errCodeUnknown, Message: err.Error()}
+	var execErr *core.ExecError
+	if errors.As(err, &execErr) {
+		cmdErr.Output = execErr.Stdout
+	}
+	return cmdErr
+}
+
+// exitCode returns the exit code documented for the code of cmdErr.
+func exitCode(cmdErr *CommandError) int {
+	code, ok := errExitCodes[cmdErr.Code]
+	if !ok {
+		return 1
+	}
+	return code
+}
+
+// ReportError reports err, as an object when a structured output is
+// requested, and returns the exit code documented for it.
+func ReportError(err error) int {
+	cmdErr := commandErrorFrom(err)
+	code := exitCode(cmdErr)
+
+	if cmdErr.Message == "" {
+		return code
+	}
+
+	if structuredOutput() {
+		printErr := printStructured(struct{ Error *CommandError }{cmdErr})
+		if printErr == nil {
+			return code
+		}
+	}
+
+	Apx.Log.Error(cmdErr.Message)
+	return code
+}
@@ -0,0 +1,103 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/vanilla-os/apx/v3/core"
+	"github.com/vanilla-os/apx/v3/settings"
+	"github.com/vanilla-os/sdk/pkg/v1/app"
+	"github.com/vanilla-os/sdk/pkg/v1/app/types"
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	Apx, err = app.NewApp(types.AppOptions{
+		Name:          "apx",
+		RDNN:          "org.vanillaos.apx",
+		LocalesFS:     os.DirFS("../../cmd/locales"),
+		DefaultLocale: "en",
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestCommandErrorFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     string
+		exitCode int
+	}{
+		{"subsystem not found", &core.NotFoundError{Err: core.ErrSubsystemNotFound, Name: "dev"}, errCodeNotFound, 3},
+		{"stack not found", &core.NotFoundError{Err: core.ErrStackNotFound, Name: "dev"}, errCodeNotFound, 3},
+		{"package manager not found", &core.NotFoundError{Err: core.ErrPkgManagerNotFound, Name: "apt"}, errCodeNotFound, 3},
+		{"container not found", &core.NotFoundError{Err: core.ErrContainerNotFound, Name: "dev"}, errCodeNotFound, 3},
+		{"image not found", &core.NotFoundError{Err: core.ErrImageNotFound, Name: "debian"}, errCodeNotFound, 3},
+		{"image not found sentinel", core.ErrImageNotFound, errCodeNotFound, 3},
+		{"subsystem exists", &core.ExistsError{Name: "dev"}, errCodeAlreadyExists, 4},
+		{"invalid subsystem name", &core.InvalidNameError{Name: "-dev", Reason: "leading dash"}, errCodeInvalidArgument, 2},
+		{"volume not found", &core.NotFoundError{Err: core.ErrVolumeNotFound, Name: "/data"}, errCodeNotFound, 3},
+		{"environment variable not set", &core.NotFoundError{Err: core.ErrEnvNotFound, Name: "JAVA_HOME"}, errCodeNotFound, 3},
+		{"invalid limit", &core.InvalidArgumentError{Argument: "IO weight", Value: "5", Reason: "it must be between 10 and 1000"}, errCodeInvalidArgument, 2},
+		{"invalid environment variable", core.ValidateEnv("KEY", "first\nsecond"), errCodeInvalidArgument, 2},
+		{"invalid volume", core.Volume{Source: "cache", Destination: "data"}.Validate(), errCodeInvalidArgument, 2},
+		{"stack missing field", &core.InvalidStackError{Path: "dev.yml", Field: "base"}, errCodeInvalidArgument, 2},
+		{"stack not parsed", &core.InvalidStackError{Path: "dev.yml", Err: errors.New("bad yaml")}, errCodeInvalidArgument, 2},
+		{"invalid pull policy", fmt.Errorf("pullPolicy: %w", settings.ErrInvalidPullPolicy), errCodeInvalidArgument, 2},
+		{"unsupported operation", &core.UnsupportedOperationError{PkgManager: "apt", Operation: "installLocal"}, errCodeUnsupported, 6},
+		{"distrobox not found", core.ErrDistroboxNotFound, errCodeUnavailable, 7},
+		{"engine not found", core.ErrEngineNotFound, errCodeUnavailable, 7},
+		{"built-in stack", core.ErrStackBuiltIn, errCodeReadOnly, 126},
+		{"built-in package manager", core.ErrPkgManagerBuiltIn, errCodeReadOnly, 126},
+		{"recreate", &core.RecreateError{Step: core.RecreateStepCreate, Err: core.ErrEngineNotFound}, errCodeUnavailable, 7},
+		{"command failed", &core.ExecError{Err: &exec.ExitError{}}, errCodeUnknown, 1},
+		{"other", errors.New("boom"), errCodeUnknown, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmdErr := commandErrorFrom(tt.err)
+			if cmdErr.Code != tt.code {
+				t.Errorf("commandErrorFrom() code = %s, want %s", cmdErr.Code, tt.code)
+			}
+			if got := exitCode(cmdErr); got != tt.exitCode {
+				t.Errorf("exitCode() = %d, want %d", got, tt.exitCode)
+			}
+
+			// the localized prefix added by the commands keeps the code
+			wrapped := commandErrorFrom(wrapError(tt.err, "subsystems.inspect.error.inspecting"))
+			if wrapped.Code != tt.code {
+				t.Errorf("wrapped code = %s, want %s", wrapped.Code, tt.code)
+			}
+			if !errors.Is(wrapped, tt.err) {
+				t.Errorf("wrapped error does not match %v", tt.err)
+			}
+		})
+	}
+}
+
+func TestWrapErrorOutput(t *testing.T) {
+	err := wrapError(&core.ExecError{Stdout: "E: Unable to locate package htop\n", Err: &exec.ExitError{}}, "runtimeCommand.error.executingCommand")
+
+	cmdErr := commandErrorFrom(err)
+	if cmdErr.Output != "E: Unable to locate package htop\n" {
+		t.Errorf("Output = %q, want the captured output", cmdErr.Output)
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+// historyPkgManagerOps are the package manager operations, run through the
+// generic helpers, which change the state of a subsystem.
+var historyPkgManagerOps = map[string]bool{
+	core.PkgManagerOpAutoRemove: true,
+	core.PkgManagerOpPurge:      true,
+	core.PkgManagerOpUpgrade:    true,
+	core.PkgManagerOpHold:       true,
+	core.PkgManagerOpUnhold:     true,
+	core.PkgManagerOpRepoAdd:    true,
+	core.PkgManagerOpRepoRemove: true,
+}
+
+func (c *HistoryCmd) Run() error {
+	entries, err := core.ListHistory(c.Subsystem)
+	if err != nil {
+		return err
+	}
+
+	if c.Json || structuredOutput() {
+		return printStructured(entries)
+	}
+
+	if len(entries) == 0 {
+		Apx.Log.Info(Apx.LC.Get("history.info.noEntries"))
+		return nil
+	}
+
+	headers := []string{Apx.LC.Get("history.labels.time"), Apx.LC.Get("search.labels.subsystem"), Apx.LC.Get("subsystems.labels.stack"), Apx.LC.Get("history.labels.command"), Apx.LC.Get("history.labels.duration"), Apx.LC.Get("history.labels.status")}
+	var data [][]string
+	for _, entry := range entries {
+		data = append(data, []string{
+			entry.Time.Local().Format(time.DateTime),
+			entry.Subsystem,
+			entry.Stack,
+			entry.Command,
+			entry.Duration.Round(time.Millisecond).String(),
+			fmt.Sprintf("%d", entry.ExitStatus),
+		})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+// withHistory runs the state-changing function fn and appends its outcome
+// to the history log, completing the given entry. Failing to write the log
+// is reported but does not affect the result of fn.
+func withHistory(entry core.HistoryEntry, fn func() error) error {
+	entry.Time = time.Now()
+	entry.Command = commandLine()
+
+	err := fn()
+
+	entry.Duration = time.Since(entry.Time)
+	if err != nil {
+		entry.ExitStatus = exitStatus(err)
+		entry.Error = err.Error()
+	}
+
+	logErr := core.LogHistory(entry)
+	if logErr != nil {
+		Apx.Log.Errorf(Apx.LC.Get("history.error.logging"), logErr)
+	}
+
+	return err
+}
+
+// exitStatus returns the exit status of a failed command: the one of the
+// package manager when it failed, the one documented for the error code
+// otherwise.
+func exitStatus(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return exitCode(commandErrorFrom(err))
+}
+
+// subSystemHistory returns a history entry for the given subsystem.
+func subSystemHistory(subSystem *core.SubSystem) core.HistoryEntry {
+	return core.HistoryEntry{
+		Subsystem: subSystem.Name,
+		Stack:     subSystem.Stack.Name,
+	}
+}
+
+// commandLine returns the command line apx was run with, quoting the
+// arguments containing spaces.
+func commandLine() string {
+	args := make([]string, len(os.Args))
+	for i, arg := range os.Args {
+		if i == 0 {
+			arg = "apx"
+		}
+
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		args[i] = arg
+	}
+
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,70 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+func (c *ImagesLoadCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) != 1 || c.Args[0] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("images.load.error.noFile"))
+	}
+
+	spinner := startSpinner(fmt.Sprintf(Apx.LC.Get("images.load.info.loading"), c.Args[0]))
+	images, err := core.LoadImages(c.Args[0])
+	spinner.Stop()
+	if err != nil {
+		return wrapError(err, "images.load.error.loading")
+	}
+
+	return printResult(images, fmt.Sprintf(Apx.LC.Get("images.load.info.success"), strings.Join(images, ", ")))
+}
+
+func (c *ImagesSaveCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) != 1 || c.Args[0] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("images.save.error.noStack"))
+	}
+
+	stack, err := core.LoadStack(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	file := c.File
+	if file == "" {
+		file = stack.Name + ".tar"
+	}
+
+	spinner := startSpinner(fmt.Sprintf(Apx.LC.Get("images.save.info.saving"), stack.Base))
+	err = stack.SaveImage(file)
+	spinner.Stop()
+	if err != nil {
+		return wrapError(err, "images.save.error.saving")
+	}
+
+	return printResult(
+		struct{ Image, Path string }{stack.Base, file},
+		fmt.Sprintf(Apx.LC.Get("images.save.info.success"), stack.Base, file),
+	)
+}
@@ -0,0 +1,132 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+func (c *SubsystemMountsListCmd) Run() error {
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	volumes := subSystem.AllVolumes()
+
+	if c.Json || structuredOutput() {
+		return printStructured(volumes)
+	}
+
+	if len(volumes) == 0 {
+		Apx.Log.Infof(Apx.LC.Get("mounts.info.noMounts"), subSystem.Name)
+		return nil
+	}
+
+	headers := []string{
+		Apx.LC.Get("mounts.labels.source"),
+		Apx.LC.Get("mounts.labels.destination"),
+		Apx.LC.Get("mounts.labels.options"),
+		Apx.LC.Get("mounts.labels.origin"),
+	}
+	var data [][]string
+	for i, volume := range volumes {
+		options := "rw"
+		if volume.ReadOnly {
+			options = "ro"
+		}
+		if volume.Relabel {
+			options += ",z"
+		}
+
+		origin := Apx.LC.Get("mounts.labels.subsystem")
+		if i < len(volumes)-len(subSystem.Volumes) {
+			origin = Apx.LC.Get("mounts.labels.stack")
+		}
+
+		data = append(data, []string{volume.Source, volume.Destination, options, origin})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+func (c *SubsystemMountsAddCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) != 2 || c.Args[0] == "" || c.Args[1] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("mounts.add.error.noPaths"))
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	volume := core.Volume{
+		Source:      c.Args[0],
+		Destination: c.Args[1],
+		ReadOnly:    c.ReadOnly,
+		Relabel:     c.Relabel,
+	}
+
+	err = volume.Validate()
+	if err != nil {
+		return wrapError(err, "mounts.error.invalid")
+	}
+
+	stopProgress, err := startProgress(fmt.Sprintf(Apx.LC.Get("mounts.info.recreating"), subSystem.Name))
+	if err != nil {
+		return err
+	}
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.AddVolume(volume)
+	})
+	stopProgress()
+	if err != nil {
+		return wrapError(err, "mounts.add.error.adding")
+	}
+
+	return printResult(subSystem.AllVolumes(), fmt.Sprintf(Apx.LC.Get("mounts.add.info.success"), volume.Source, volume.Destination, subSystem.Name))
+}
+
+func (c *SubsystemMountsRemoveCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) != 1 || c.Args[0] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("mounts.remove.error.noDestination"))
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	stopProgress, err := startProgress(fmt.Sprintf(Apx.LC.Get("mounts.info.recreating"), subSystem.Name))
+	if err != nil {
+		return err
+	}
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.RemoveVolume(c.Args[0])
+	})
+	stopProgress()
+	if err != nil {
+		return wrapError(err, "mounts.remove.error.removing")
+	}
+
+	return printResult(subSystem.AllVolumes(), fmt.Sprintf(Apx.LC.Get("mounts.remove.info.success"), c.Args[0], subSystem.Name))
+}
@@ -0,0 +1,146 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+// OutdatedExitCode is the exit code of "apx outdated" when at least one
+// subsystem has pending updates, so that it can drive scripts.
+const OutdatedExitCode = 100
+
+// outdatedReport is the result of the check of a subsystem. Unsupported
+// is set when its package manager cannot list the upgradable packages, so
+// the subsystem was skipped.
+type outdatedReport struct {
+	Subsystem   string
+	Stack       string
+	Packages    []core.UpgradablePackage
+	Unsupported bool   `json:",omitempty"`
+	Error       string `json:",omitempty"`
+}
+
+func (c *OutdatedCmd) Run() error {
+	subSystems, err := core.ListSubSystems(false, false)
+	if err != nil {
+		return err
+	}
+
+	if len(subSystems) == 0 && !c.Json && !structuredOutput() {
+		Apx.Log.Info(Apx.LC.Get("subsystems.list.info.noSubsystems"))
+		return nil
+	}
+
+	var reports []outdatedReport
+	if c.Json || structuredOutput() {
+		reports = checkOutdated(subSystems)
+	} else {
+		spinner := startSpinner(Apx.LC.Get("outdated.info.checking"))
+		reports = checkOutdated(subSystems)
+		spinner.Stop()
+	}
+
+	hasUpdates, hasErrors := false, false
+	for _, report := range reports {
+		if report.Error != "" {
+			hasErrors = true
+		}
+		if len(report.Packages) > 0 {
+			hasUpdates = true
+		}
+	}
+
+	if c.Json || structuredOutput() {
+		err := printStructured(reports)
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, report := range reports {
+			if report.Error != "" {
+				Apx.Log.Errorf(Apx.LC.Get("outdated.error.checking"), report.Subsystem, report.Error)
+				continue
+			}
+
+			if report.Unsupported {
+				Apx.Log.Infof(Apx.LC.Get("outdated.info.unsupported"), report.Subsystem)
+				continue
+			}
+
+			if len(report.Packages) == 0 {
+				Apx.Log.Infof(Apx.LC.Get("outdated.info.upToDate"), report.Subsystem)
+				continue
+			}
+
+			Apx.Log.Infof(Apx.LC.Get("outdated.info.foundUpdates"), report.Subsystem, len(report.Packages))
+
+			headers := []string{Apx.LC.Get("outdated.labels.package"), Apx.LC.Get("outdated.labels.current"), Apx.LC.Get("outdated.labels.candidate")}
+			var data [][]string
+			for _, pkg := range report.Packages {
+				data = append(data, []string{pkg.Name, pkg.Current, pkg.Candidate})
+			}
+
+			err := Apx.CLI.Table(headers, data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// the reports already tell what happened, the errors only carry the
+	// exit code. Found updates win, the failed checks are in the reports.
+	if hasUpdates {
+		return newCommandError(errCodeOutdated, "")
+	}
+	if hasErrors {
+		return newCommandError(errCodeUnknown, "")
+	}
+
+	return nil
+}
+
+// checkOutdated collects the upgradable packages of the given subsystems in
+// parallel, since refreshing the metadata is mostly network bound.
+func checkOutdated(subSystems []*core.SubSystem) []outdatedReport {
+	reports := make([]outdatedReport, len(subSystems))
+
+	var wg sync.WaitGroup
+	for i, subSystem := range subSystems {
+		wg.Add(1)
+		go func(i int, subSystem *core.SubSystem) {
+			defer wg.Done()
+
+			report := outdatedReport{
+				Subsystem: subSystem.Name,
+				Stack:     subSystem.Stack.Name,
+				Packages:  []core.UpgradablePackage{},
+			}
+
+			packages, err := subSystem.ListUpgradable()
+			switch {
+			case errors.Is(err, core.ErrUnsupportedOperation):
+				report.Unsupported = true
+			case err != nil:
+				report.Error = err.Error()
+			default:
+				report.Packages = packages
+			}
+
+			reports[i] = report
+		}(i, subSystem)
+	}
+	wg.Wait()
+
+	return reports
+}
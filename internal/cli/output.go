@@ -0,0 +1,107 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/vanilla-os/sdk/pkg/v1/cli"
+	"gopkg.in/yaml.v2"
+)
+
+// Values accepted by the global --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func outputFormat() string {
+	if Apx.CLI == nil {
+		return outputTable
+	}
+
+	root, ok := Apx.CLI.GetRoot().(*RootCmd)
+	if !ok || root.Output == "" {
+		return outputTable
+	}
+	return root.Output
+}
+
+// structuredOutput reports whether the output of the commands must be
+// machine readable instead of tables and log lines.
+func structuredOutput() bool {
+	return outputFormat() != outputTable
+}
+
+// startSpinner starts a spinner, unless a structured output is requested
+// since the spinner is drawn on stdout too.
+func startSpinner(message string) *cli.SpinnerModel {
+	if structuredOutput() {
+		return &cli.SpinnerModel{}
+	}
+	return Apx.CLI.StartSpinner(message)
+}
+
+// confirmAction asks the user to confirm msg. Nobody could answer with a
+// structured output, so the command fails instead and has to be run with
+// --force.
+func confirmAction(msg string) (bool, error) {
+	if structuredOutput() {
+		return false, errConfirmationRequired()
+	}
+	return Apx.CLI.ConfirmAction(msg, "y", "N", false)
+}
+
+func errConfirmationRequired() error {
+	return newCommandError(errCodeInvalidArgument, Apx.LC.Get("apx.errors.confirmationRequired"))
+}
+
+// printStructured prints v as JSON, or as YAML when --output=yaml is
+// passed. The YAML document is converted from the JSON one, so both
+// formats share the same field names.
+func printStructured(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	switch outputFormat() {
+	case outputTable, outputJSON:
+		fmt.Println(string(data))
+		return nil
+	case outputYAML:
+		var doc any
+		err = yaml.Unmarshal(data, &doc)
+		if err != nil {
+			return err
+		}
+		data, err = yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("apx.errors.unknownOutput"), outputFormat()))
+}
+
+// printResult prints the outcome of a command changing the state when a
+// structured output is requested, the message is logged otherwise.
+func printResult(v any, message string) error {
+	if structuredOutput() {
+		return printStructured(v)
+	}
+
+	Apx.Log.Info(message)
+	return nil
+}
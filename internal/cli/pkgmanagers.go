@@ -10,9 +10,7 @@ package cli
 */
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/vanilla-os/apx/v3/core"
@@ -47,7 +45,7 @@ var PkgManagerCmdSetOrder = []string{
 func (c *PkgManagersListCmd) Run() error {
 	pkgManagers := core.ListPkgManagers()
 
-	if !c.Json {
+	if !c.Json && !structuredOutput() {
 		pkgManagersCount := len(pkgManagers)
 		if pkgManagersCount == 0 {
 			Apx.Log.Info(Apx.LC.Get("pkgmanagers.list.info.noPkgManagers"))
@@ -71,8 +69,7 @@ func (c *PkgManagersListCmd) Run() error {
 			return err
 		}
 	} else {
-		jsonPkgManagers, _ := json.MarshalIndent(pkgManagers, "", "  ")
-		fmt.Println(string(jsonPkgManagers))
+		return printStructured(pkgManagers)
 	}
 
 	return nil
@@ -81,13 +78,16 @@ func (c *PkgManagersListCmd) Run() error {
 func (c *PkgManagersShowCmd) Run() error {
 	args := c.Args
 	if len(args) == 0 {
-		return fmt.Errorf("package manager name required")
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.show.error.noName"))
 	}
 	pkgManagerName := args[0]
 	pkgManager, err := core.LoadPkgManager(pkgManagerName)
 	if err != nil {
-		Apx.Log.Error(err.Error())
-		return nil
+		return err
+	}
+
+	if structuredOutput() {
+		return printStructured(pkgManager)
 	}
 
 	headers := []string{"Property", "Value"}
@@ -104,6 +104,17 @@ func (c *PkgManagersShowCmd) Run() error {
 		{"Show", pkgManager.CmdShow},
 		{"Update", pkgManager.CmdUpdate},
 		{"Upgrade", pkgManager.CmdUpgrade},
+		{"InstallLocal", pkgManager.CmdInstallLocal},
+		{"Hold", pkgManager.CmdHold},
+		{"Unhold", pkgManager.CmdUnhold},
+		{"ListUpgradable", pkgManager.CmdListUpgradable},
+		{"RepoAdd", pkgManager.CmdRepoAdd},
+		{"RepoRemove", pkgManager.CmdRepoRemove},
+		{"Owns", pkgManager.CmdOwns},
+		{"NonInteractiveFlag", pkgManager.NonInteractiveFlag},
+		{"NonInteractiveEnv", pkgManager.NonInteractiveEnv},
+		{"NonInteractiveGlobal", fmt.Sprintf("%t", pkgManager.NonInteractiveGlobal)},
+		{"Capabilities", strings.Join(pkgManager.Capabilities(), ", ")},
 	}
 
 	err = Apx.CLI.Table(headers, data)
@@ -115,11 +126,14 @@ func (c *PkgManagersShowCmd) Run() error {
 }
 
 func (c *PkgManagersNewCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
 
 	if c.Name == "" {
 		if c.NoPrompt {
-			Apx.Log.Error(Apx.LC.Get("pkgmanagers.new.error.noName"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.new.error.noName"))
 		}
 
 		name, err := Apx.CLI.PromptText(Apx.LC.Get("pkgmanagers.new.info.askName"), "")
@@ -130,8 +144,7 @@ func (c *PkgManagersNewCmd) Run() error {
 		c.Name = strings.ReplaceAll(c.Name, "\n", "")
 		c.Name = strings.ReplaceAll(c.Name, " ", "")
 		if c.Name == "" {
-			Apx.Log.Error(Apx.LC.Get("pkgmanagers.new.error.emptyName"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.new.error.emptyName"))
 		}
 	}
 
@@ -164,8 +177,7 @@ func (c *PkgManagersNewCmd) Run() error {
 		cmd := cmdMap[cmdName]
 		if *cmd == "" {
 			if c.NoPrompt {
-				Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.new.error.noCommand"), cmdName)
-				return nil
+				return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.new.error.noCommand"), cmdName))
 			}
 			if cmdName == PkgManagerCmdPurge || cmdName == PkgManagerCmdAutoRemove {
 				answer, err := Apx.CLI.PromptText(fmt.Sprintf(Apx.LC.Get("pkgmanagers.new.info.askCommandWithDefault"), cmdName, c.Remove), c.Remove)
@@ -185,16 +197,14 @@ func (c *PkgManagersNewCmd) Run() error {
 			}
 			*cmd = strings.TrimSpace(answer)
 			if *cmd == "" {
-				Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.new.error.emptyCommand"), cmdName)
-				return nil
+				return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.new.error.emptyCommand"), cmdName))
 			}
 		}
 	}
 
 	if core.PkgManagerExists(c.Name) {
 		if c.NoPrompt {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.new.error.alreadyExists"), c.Name)
-			return nil
+			return newCommandError(errCodeAlreadyExists, fmt.Sprintf(Apx.LC.Get("pkgmanagers.new.error.alreadyExists"), c.Name))
 		}
 
 		confirm, err := Apx.CLI.ConfirmAction(
@@ -213,21 +223,32 @@ func (c *PkgManagersNewCmd) Run() error {
 	}
 
 	pkgManager := core.NewPkgManager(c.Name, c.NeedSudo, c.AutoRemove, c.Clean, c.Install, c.List, c.Purge, c.Remove, c.Search, c.Show, c.Update, c.Upgrade, false)
-	err := pkgManager.Save()
+	pkgManager.CmdInstallLocal = c.InstallLocal
+	pkgManager.CmdHold = c.Hold
+	pkgManager.CmdUnhold = c.Unhold
+	pkgManager.CmdListUpgradable = c.ListUpgradable
+	pkgManager.CmdRepoAdd = c.RepoAdd
+	pkgManager.CmdRepoRemove = c.RepoRemove
+	pkgManager.CmdOwns = c.Owns
+	pkgManager.NonInteractiveFlag = c.NonInteractiveFlag
+	pkgManager.NonInteractiveEnv = c.NonInteractiveEnv
+	pkgManager.NonInteractiveGlobal = c.NonInteractiveGlobal
+	err = withHistory(core.HistoryEntry{PkgManager: pkgManager.Name}, pkgManager.Save)
 	if err != nil {
-		Apx.Log.Error(err.Error())
-		return nil
+		return err
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("pkgmanagers.new.success"), c.Name)
-
-	return nil
+	return printResult(pkgManager, fmt.Sprintf(Apx.LC.Get("pkgmanagers.new.success"), c.Name))
 }
 
 func (c *PkgManagersRmCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Name == "" {
-		Apx.Log.Error(Apx.LC.Get("pkgmanagers.rm.error.noName"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.rm.error.noName"))
 	}
 
 	pkgManager, error := core.LoadPkgManager(c.Name)
@@ -237,18 +258,19 @@ func (c *PkgManagersRmCmd) Run() error {
 
 	stacks := core.ListStackForPkgManager(pkgManager.Name)
 	if len(stacks) > 0 {
-		Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.rm.error.inUse"), len(stacks))
-		headers := []string{Apx.LC.Get("pkgmanagers.labels.name"), "Base", "Packages", "PkgManager", Apx.LC.Get("pkgmanagers.labels.builtIn")}
-		var data [][]string
-		for _, stack := range stacks {
-			builtIn := Apx.LC.Get("apx.terminal.no")
-			if stack.BuiltIn {
-				builtIn = Apx.LC.Get("apx.terminal.yes")
+		if !structuredOutput() {
+			headers := []string{Apx.LC.Get("pkgmanagers.labels.name"), "Base", "Packages", "PkgManager", Apx.LC.Get("pkgmanagers.labels.builtIn")}
+			var data [][]string
+			for _, stack := range stacks {
+				builtIn := Apx.LC.Get("apx.terminal.no")
+				if stack.BuiltIn {
+					builtIn = Apx.LC.Get("apx.terminal.yes")
+				}
+				data = append(data, []string{stack.Name, stack.Base, strings.Join(stack.Packages, ", "), stack.PkgManager, builtIn})
 			}
-			data = append(data, []string{stack.Name, stack.Base, strings.Join(stack.Packages, ", "), stack.PkgManager, builtIn})
+			Apx.CLI.Table(headers, data)
 		}
-		Apx.CLI.Table(headers, data)
-		return nil
+		return newCommandError(errCodeInUse, fmt.Sprintf(Apx.LC.Get("pkgmanagers.rm.error.inUse"), len(stacks)))
 	}
 
 	if !c.Force {
@@ -266,19 +288,22 @@ func (c *PkgManagersRmCmd) Run() error {
 		}
 	}
 
-	error = pkgManager.Remove()
+	error = withHistory(core.HistoryEntry{PkgManager: pkgManager.Name}, pkgManager.Remove)
 	if error != nil {
 		return error
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("pkgmanagers.rm.info.success"), c.Name)
-	return nil
+	return printResult(pkgManager, fmt.Sprintf(Apx.LC.Get("pkgmanagers.rm.info.success"), c.Name))
 }
 
 func (c *PkgManagersExportCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Name == "" {
-		Apx.Log.Error(Apx.LC.Get("pkgmanagers.export.error.noName"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.export.error.noName"))
 	}
 
 	pkgManager, error := core.LoadPkgManager(c.Name)
@@ -286,46 +311,54 @@ func (c *PkgManagersExportCmd) Run() error {
 		return error
 	}
 
-	if c.Output == "" {
-		Apx.Log.Error(Apx.LC.Get("pkgmanagers.export.error.noOutput"))
-		return nil
+	if c.File == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.export.error.noOutput"))
 	}
 
-	error = pkgManager.Export(c.Output)
+	error = pkgManager.Export(c.File)
 	if error != nil {
 		return error
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("pkgmanagers.export.info.success"), pkgManager.Name, c.Output)
-	return nil
+	return printResult(
+		struct{ PkgManager, Path string }{pkgManager.Name, c.File},
+		fmt.Sprintf(Apx.LC.Get("pkgmanagers.export.info.success"), pkgManager.Name, c.File),
+	)
 }
 
 func (c *PkgManagersImportCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Input == "" {
-		Apx.Log.Error(Apx.LC.Get("pkgmanagers.import.error.noInput"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.import.error.noInput"))
 	}
 
 	pkgmanager, error := core.LoadPkgManagerFromPath(c.Input)
 	if error != nil {
-		Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.import.error.cannotLoad"), c.Input)
+		return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.import.error.cannotLoad"), c.Input))
 	}
 
-	error = pkgmanager.Save()
+	error = withHistory(core.HistoryEntry{PkgManager: pkgmanager.Name}, pkgmanager.Save)
 	if error != nil {
 		return error
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("pkgmanagers.import.info.success"), pkgmanager.Name)
-	return nil
+	return printResult(pkgmanager, fmt.Sprintf(Apx.LC.Get("pkgmanagers.import.info.success"), pkgmanager.Name))
 }
 
 func (c *PkgManagersUpdateCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	args := c.Args
 	if c.Name == "" {
 		if len(args) != 1 || args[0] == "" {
-			Apx.Log.Error(Apx.LC.Get("pkgmanagers.update.error.noName"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("pkgmanagers.update.error.noName"))
 		}
 
 		c.Name = args[0]
@@ -337,8 +370,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 	}
 
 	if pkgmanager.BuiltIn {
-		Apx.Log.Error(Apx.LC.Get("pkgmanagers.update.error.builtIn"))
-		os.Exit(126)
+		return newCommandError(errCodeReadOnly, Apx.LC.Get("pkgmanagers.update.error.builtIn"))
 	}
 
 	if c.AutoRemove == "" {
@@ -352,8 +384,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.AutoRemove = pkgmanager.CmdAutoRemove
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "autoRemove")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "autoRemove"))
 		}
 	}
 
@@ -368,8 +399,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Clean = pkgmanager.CmdClean
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "clean")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "clean"))
 		}
 	}
 
@@ -384,8 +414,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Install = pkgmanager.CmdInstall
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "install")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "install"))
 		}
 	}
 
@@ -400,8 +429,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.List = pkgmanager.CmdList
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "list")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "list"))
 		}
 	}
 
@@ -416,8 +444,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Purge = pkgmanager.CmdPurge
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "purge")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "purge"))
 		}
 	}
 
@@ -432,8 +459,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Remove = pkgmanager.CmdRemove
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "remove")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "remove"))
 		}
 	}
 
@@ -448,8 +474,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Search = pkgmanager.CmdSearch
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "search")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "search"))
 		}
 	}
 
@@ -464,8 +489,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Show = pkgmanager.CmdShow
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "show")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "show"))
 		}
 	}
 
@@ -480,8 +504,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Update = pkgmanager.CmdUpdate
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "update")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "update"))
 		}
 	}
 
@@ -496,8 +519,7 @@ func (c *PkgManagersUpdateCmd) Run() error {
 				c.Upgrade = pkgmanager.CmdUpgrade
 			}
 		} else {
-			Apx.Log.Errorf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "upgrade")
-			return nil
+			return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("pkgmanagers.update.error.missingCommand"), "upgrade"))
 		}
 	}
 
@@ -513,12 +535,43 @@ func (c *PkgManagersUpdateCmd) Run() error {
 	pkgmanager.CmdUpdate = c.Update
 	pkgmanager.CmdUpgrade = c.Upgrade
 
-	err := pkgmanager.Save()
+	// the following settings are optional, so they are never prompted and
+	// are only replaced when explicitly passed
+	if c.InstallLocal != "" {
+		pkgmanager.CmdInstallLocal = c.InstallLocal
+	}
+	if c.Hold != "" {
+		pkgmanager.CmdHold = c.Hold
+	}
+	if c.Unhold != "" {
+		pkgmanager.CmdUnhold = c.Unhold
+	}
+	if c.ListUpgradable != "" {
+		pkgmanager.CmdListUpgradable = c.ListUpgradable
+	}
+	if c.RepoAdd != "" {
+		pkgmanager.CmdRepoAdd = c.RepoAdd
+	}
+	if c.RepoRemove != "" {
+		pkgmanager.CmdRepoRemove = c.RepoRemove
+	}
+	if c.Owns != "" {
+		pkgmanager.CmdOwns = c.Owns
+	}
+	if c.NonInteractiveFlag != "" {
+		pkgmanager.NonInteractiveFlag = c.NonInteractiveFlag
+	}
+	if c.NonInteractiveEnv != "" {
+		pkgmanager.NonInteractiveEnv = c.NonInteractiveEnv
+	}
+	if c.NonInteractiveGlobal {
+		pkgmanager.NonInteractiveGlobal = true
+	}
+
+	err = withHistory(core.HistoryEntry{PkgManager: pkgmanager.Name}, pkgmanager.Save)
 	if err != nil {
 		return err
 	}
 
-	Apx.Log.Term.Info().Msgf(Apx.LC.Get("pkgmanagers.new.success"), c.Name)
-
-	return nil
+	return printResult(pkgmanager, fmt.Sprintf(Apx.LC.Get("pkgmanagers.new.success"), c.Name))
 }
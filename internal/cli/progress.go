@@ -0,0 +1,78 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+// Values accepted by the global --progress flag. progressJSON prints the
+// progress events as JSON lines, for graphical frontends.
+const (
+	progressAuto = "auto"
+	progressJSON = "json"
+)
+
+func progressMode() string {
+	root, ok := Apx.CLI.GetRoot().(*RootCmd)
+	if !ok || root.Progress == "" {
+		return progressAuto
+	}
+	return root.Progress
+}
+
+// startProgress shows the progress of a long operation, either as a
+// spinner describing the current phase or as a stream of JSON events on
+// stderr when --progress=json is passed, so that they are not mixed with
+// the structured output. The returned function stops it.
+func startProgress(message string) (func(), error) {
+	switch progressMode() {
+	case progressJSON:
+		encoder := json.NewEncoder(os.Stderr)
+		core.SetProgressHandler(func(event core.ProgressEvent) {
+			_ = encoder.Encode(event)
+		})
+		return func() {
+			core.SetProgressHandler(nil)
+		}, nil
+	case progressAuto:
+	default:
+		return nil, newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("apx.errors.unknownProgress"), progressMode()))
+	}
+
+	spinner := startSpinner(message)
+	core.SetProgressHandler(func(event core.ProgressEvent) {
+		spinner.UpdateMessage(fmt.Sprintf("%s %s", message, progressMessage(event)))
+	})
+	return func() {
+		core.SetProgressHandler(nil)
+		spinner.Stop()
+	}, nil
+}
+
+// progressMessage describes the event in a single line.
+func progressMessage(event core.ProgressEvent) string {
+	msg := Apx.LC.Get("progress.phase." + event.Phase)
+
+	switch {
+	case event.Unit == core.ProgressUnitBytes && event.Total > 0:
+		msg += fmt.Sprintf(" %d%% (%s / %s)", event.Percent(), formatSize(event.Current), formatSize(event.Total))
+	case event.Unit == core.ProgressUnitLayers && event.Total > 0:
+		msg += " " + fmt.Sprintf(Apx.LC.Get("progress.layers"), event.Current, event.Total)
+	case event.Message != "":
+		msg += ": " + event.Message
+	}
+
+	return msg
+}
@@ -10,7 +10,11 @@ package cli
 */
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/vanilla-os/apx/v3/core"
 )
@@ -18,112 +22,474 @@ import (
 // RootCmd does not have a Run method to trigger help automatically
 
 func (c *SubsystemEnterCmd) Run() error {
-	subSystem, err := core.LoadSubSystem(c.Name, false)
+	subSystem, err := loadSubSystem(c.Name)
 	if err != nil {
 		return err
 	}
 	err = subSystem.Enter()
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.enteringContainer"), err)
+		return wrapError(err, "runtimeCommand.error.enteringContainer")
 	}
 	return nil
 }
 
 func (c *SubsystemRunCmd) Run() error {
-	subSystem, err := core.LoadSubSystem(c.Name, false)
+	subSystem, err := loadSubSystem(c.Name)
 	if err != nil {
 		return err
 	}
 	_, err = subSystem.Exec(false, false, c.Args...)
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.executingCommand"), err)
+		return wrapError(err, "runtimeCommand.error.executingCommand")
 	}
 	return nil
 }
 
+// packagesResult describes the packages installed or removed by a command
+// in the structured output, along with the applications exported or
+// unexported for them.
+type packagesResult struct {
+	Subsystem string
+	Packages  []string
+	Apps      int
+}
+
+// syncResult describes the outcome of a sync in the structured output.
+// Kept are the pruned packages which stack packages depend on, so they are
+// still installed.
+type syncResult struct {
+	*core.StackDiff
+	Kept []string `json:",omitempty"`
+}
+
 func (c *SubsystemInstallCmd) Run() error {
-	subSystem, err := core.LoadSubSystem(c.Name, false)
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	subSystem, err := loadSubSystem(c.Name)
 	if err != nil {
 		return err
 	}
 
 	pkgManager, err := subSystem.Stack.GetPkgManager()
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.cantAccessPkgManager"), err)
+		return wrapError(err, "runtimeCommand.error.cantAccessPkgManager")
+	}
+
+	// package files from the host are copied to the user cache, which is
+	// shared with the container, and installed with CmdInstallLocal
+	packages := []string{}
+	localPackages := []string{}
+	exportNames := []string{}
+	for _, arg := range c.Args {
+		if !core.IsLocalPackage(arg) {
+			packages = append(packages, arg)
+			exportNames = append(exportNames, arg)
+			continue
+		}
+
+		if !pkgManager.Supports(core.PkgManagerOpInstallLocal) {
+			return newCommandError(errCodeUnsupported, fmt.Sprintf(Apx.LC.Get("runtimeCommand.error.noInstallLocal"), pkgManager.Name))
+		}
+
+		tmpPath, err := core.CopyToUserTemp(arg)
+		if err != nil {
+			return wrapError(err, "runtimeCommand.error.copyingLocalPackage", arg)
+		}
+		defer os.Remove(tmpPath)
+
+		localPackages = append(localPackages, tmpPath)
+		exportNames = append(exportNames, core.LocalPackageName(arg))
 	}
 
-	finalArgs := pkgManager.GenCmd(pkgManager.CmdInstall, c.Args...)
-	_, err = subSystem.Exec(false, false, finalArgs...)
+	cmdStr := ""
+	if len(packages) > 0 || len(localPackages) == 0 {
+		cmdStr, err = pkgManagerCommands(pkgManager, core.PkgManagerOpInstall)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.RecordTransaction(core.TransactionInstall, exportNames, func() error {
+			if cmdStr != "" {
+				finalArgs := pkgManager.GenCmd(cmdStr, packages...)
+				_, err := subSystem.Exec(structuredOutput(), false, finalArgs...)
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(localPackages) > 0 {
+				finalArgs := pkgManager.GenCmd(pkgManager.CmdInstallLocal, localPackages...)
+				_, err := subSystem.Exec(structuredOutput(), false, finalArgs...)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	})
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.executingCommand"), err)
+		return wrapError(err, "runtimeCommand.error.executingCommand")
 	}
 
+	// the installed packages may provide previously missing commands
+	_ = core.ClearCommandNotFoundCache()
+
+	result := packagesResult{Subsystem: subSystem.Name, Packages: c.Args}
 	if !c.NoExport {
-		exportedN, err := subSystem.ExportDesktopEntries(c.Args...)
+		exportedN, err := subSystem.ExportDesktopEntries(exportNames...)
 		if err == nil {
-			Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.exportedApps"), exportedN)
+			result.Apps = exportedN
+			if !structuredOutput() {
+				Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.exportedApps"), exportedN)
+			}
 		}
 	}
+
+	if structuredOutput() {
+		return printStructured(result)
+	}
 	return nil
 }
 
 func (c *SubsystemRemoveCmd) Run() error {
-	subSystem, err := core.LoadSubSystem(c.Name, false)
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	subSystem, err := loadSubSystem(c.Name)
 	if err != nil {
 		return err
 	}
 
 	pkgManager, err := subSystem.Stack.GetPkgManager()
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.cantAccessPkgManager"), err)
+		return wrapError(err, "runtimeCommand.error.cantAccessPkgManager")
+	}
+
+	cmdStr, err := pkgManagerCommands(pkgManager, core.PkgManagerOpRemove)
+	if err != nil {
+		return err
 	}
 
+	result := packagesResult{Subsystem: subSystem.Name, Packages: c.Args}
 	exportedN, err := subSystem.UnexportDesktopEntries(c.Args...)
 	if err == nil {
-		Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.unexportedApps"), exportedN)
+		result.Apps = exportedN
+		if !structuredOutput() {
+			Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.unexportedApps"), exportedN)
+		}
 	}
 
-	finalArgs := pkgManager.GenCmd(pkgManager.CmdRemove, c.Args...)
-	_, err = subSystem.Exec(false, false, finalArgs...)
+	finalArgs := pkgManager.GenCmd(cmdStr, c.Args...)
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.RecordTransaction(core.TransactionRemove, c.Args, func() error {
+			_, err := subSystem.Exec(structuredOutput(), false, finalArgs...)
+			return err
+		})
+	})
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.executingCommand"), err)
+		return wrapError(err, "runtimeCommand.error.executingCommand")
+	}
+
+	_ = core.ClearCommandNotFoundCache()
+	if structuredOutput() {
+		return printStructured(result)
 	}
 	return nil
 }
 
+func (c *SubsystemUndoCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	transaction, err := core.LastTransaction(subSystem)
+	if err != nil {
+		return err
+	}
+	if transaction == nil {
+		Apx.Log.Info(Apx.LC.Get("runtimeCommand.info.nothingToUndo"))
+		return nil
+	}
+
+	if !c.Force {
+		msg := fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.askUndo"), transaction.Operation, transaction.Time.Local().Format(time.DateTime))
+		if len(transaction.Added) > 0 {
+			msg += "\n" + fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.undoRemoves"), strings.Join(transaction.Added, ", "))
+		}
+		if len(transaction.Removed) > 0 {
+			msg += "\n" + fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.undoInstalls"), strings.Join(transaction.Removed, ", "))
+		}
+
+		confirm, err := confirmAction(msg)
+		if err != nil {
+			return err
+		}
+		if !confirm {
+			Apx.Log.Info(Apx.LC.Get("apx.info.aborting"))
+			return nil
+		}
+	}
+
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		_, err := subSystem.Undo()
+		return err
+	})
+
+	var conflict *core.TransactionConflictError
+	if errors.As(err, &conflict) {
+		msg := Apx.LC.Get("runtimeCommand.error.undoConflict")
+		if len(conflict.Missing) > 0 {
+			msg += "\n" + fmt.Sprintf(Apx.LC.Get("runtimeCommand.error.undoMissing"), strings.Join(conflict.Missing, ", "))
+		}
+		if len(conflict.Reinstalled) > 0 {
+			msg += "\n" + fmt.Sprintf(Apx.LC.Get("runtimeCommand.error.undoReinstalled"), strings.Join(conflict.Reinstalled, ", "))
+		}
+		return newCommandError(errCodeConflict, msg)
+	}
+	if errors.Is(err, core.ErrNothingToUndo) {
+		Apx.Log.Info(Apx.LC.Get("runtimeCommand.info.nothingToUndo"))
+		return nil
+	}
+	if err != nil {
+		return wrapError(err, "runtimeCommand.error.executingCommand")
+	}
+
+	_ = core.ClearCommandNotFoundCache()
+	return printResult(transaction, Apx.LC.Get("runtimeCommand.info.undone"))
+}
+
+func (c *SubsystemDiffCmd) Run() error {
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	diff, err := subSystem.DiffStack()
+	if err != nil {
+		return wrapError(err, "runtimeCommand.error.diffingStack")
+	}
+
+	if c.Json || structuredOutput() {
+		return printStructured(diff)
+	}
+
+	if len(diff.Missing) == 0 && len(diff.Extra) == 0 {
+		Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.inSync"), subSystem.Stack.Name)
+		return nil
+	}
+
+	headers := []string{Apx.LC.Get("search.labels.package"), Apx.LC.Get("subsystems.labels.status")}
+	var data [][]string
+	for _, pkg := range diff.Missing {
+		data = append(data, []string{pkg, Apx.LC.Get("runtimeCommand.labels.missing")})
+	}
+	for _, pkg := range diff.Extra {
+		data = append(data, []string{pkg, Apx.LC.Get("runtimeCommand.labels.extra")})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+func (c *SubsystemSyncCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	pkgManager, err := subSystem.Stack.GetPkgManager()
+	if err != nil {
+		return wrapError(err, "runtimeCommand.error.cantAccessPkgManager")
+	}
+
+	diff, err := subSystem.DiffStack()
+	if err != nil {
+		return wrapError(err, "runtimeCommand.error.diffingStack")
+	}
+
+	result := syncResult{StackDiff: diff}
+	prune := c.Prune && len(diff.Extra) > 0
+	if len(diff.Missing) == 0 && !prune {
+		return printResult(diff, fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.inSync"), subSystem.Stack.Name))
+	}
+
+	// fail before installing the missing packages, which happens before
+	// asking to prune
+	if prune && !c.Force && structuredOutput() {
+		return errConfirmationRequired()
+	}
+
+	if len(diff.Missing) > 0 {
+		cmdStr, err := pkgManagerCommands(pkgManager, core.PkgManagerOpInstall)
+		if err != nil {
+			return err
+		}
+
+		Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.installingMissing"), strings.Join(diff.Missing, ", "))
+		err = withHistory(subSystemHistory(subSystem), func() error {
+			return subSystem.RecordTransaction(core.TransactionInstall, nil, func() error {
+				_, err := subSystem.Exec(structuredOutput(), false, pkgManager.GenCmd(cmdStr, diff.Missing...)...)
+				return err
+			})
+		})
+		if err != nil {
+			return wrapError(err, "runtimeCommand.error.executingCommand")
+		}
+	}
+
+	if prune {
+		cmdStr, err := pkgManagerCommands(pkgManager, core.PkgManagerOpRemove)
+		if err != nil {
+			return err
+		}
+
+		if !c.Force {
+			confirm, err := confirmAction(fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.askPrune"), strings.Join(diff.Extra, ", ")))
+			if err != nil {
+				return err
+			}
+			if !confirm {
+				Apx.Log.Info(Apx.LC.Get("apx.info.aborting"))
+				return nil
+			}
+		}
+
+		installCmdStr, err := pkgManagerCommands(pkgManager, core.PkgManagerOpInstall)
+		if err != nil {
+			return err
+		}
+
+		_, _ = subSystem.UnexportDesktopEntries(diff.Extra...)
+		err = withHistory(subSystemHistory(subSystem), func() error {
+			return subSystem.RecordTransaction(core.TransactionRemove, diff.Extra, func() error {
+				_, err := subSystem.Exec(structuredOutput(), false, pkgManager.GenCmd(cmdStr, diff.Extra...)...)
+				if err != nil {
+					return err
+				}
+
+				// the package manager may have removed the stack packages
+				// depending on the pruned ones, installing them back keeps
+				// their dependencies and leaves them out of the transaction
+				after, err := subSystem.DiffStack()
+				if err != nil || len(after.Missing) == 0 {
+					return err
+				}
+				_, err = subSystem.Exec(structuredOutput(), false, pkgManager.GenCmd(installCmdStr, after.Missing...)...)
+				return err
+			})
+		})
+		if err != nil {
+			return wrapError(err, "runtimeCommand.error.executingCommand")
+		}
+
+		installed, err := subSystem.InstalledPackages()
+		if err == nil {
+			installedSet := map[string]bool{}
+			for _, pkg := range installed {
+				installedSet[pkg] = true
+			}
+			for _, pkg := range diff.Extra {
+				if installedSet[pkg] {
+					result.Kept = append(result.Kept, pkg)
+				}
+			}
+		}
+		if len(result.Kept) > 0 && !structuredOutput() {
+			Apx.Log.Warnf(Apx.LC.Get("runtimeCommand.info.keptDependencies"), strings.Join(result.Kept, ", "))
+		}
+	}
+
+	_ = core.ClearCommandNotFoundCache()
+	return printResult(result, fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.synced"), subSystem.Stack.Name))
+}
+
 func (c *SubsystemUpdateCmd) Run() error {
-	return genericPkgManagerCommand(c.Name, "update")
+	return genericPkgManagerCommand(c.Name, core.PkgManagerOpUpdate)
 }
 
 func (c *SubsystemUpgradeCmd) Run() error {
-	return genericPkgManagerCommand(c.Name, "upgrade")
+	return genericPkgManagerCommand(c.Name, core.PkgManagerOpUpgrade)
 }
 
 func (c *SubsystemListCmd) Run() error {
-	return genericPkgManagerCommand(c.Name, "list")
+	return genericPkgManagerCommand(c.Name, core.PkgManagerOpList)
 }
 
 func (c *SubsystemSearchCmd) Run() error {
-	return genericPkgManagerArgsCommand(c.Name, "search", c.Args)
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpSearch, c.Args)
 }
 
 func (c *SubsystemShowCmd) Run() error {
-	return genericPkgManagerArgsCommand(c.Name, "show", c.Args)
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpShow, c.Args)
 }
 
 func (c *SubsystemAutoRemoveCmd) Run() error {
-	return genericPkgManagerCommand(c.Name, "autoremove")
+	return genericPkgManagerCommand(c.Name, core.PkgManagerOpAutoRemove)
 }
 
 func (c *SubsystemCleanCmd) Run() error {
-	return genericPkgManagerCommand(c.Name, "clean")
+	return genericPkgManagerCommand(c.Name, core.PkgManagerOpClean)
 }
 
 func (c *SubsystemPurgeCmd) Run() error {
-	return genericPkgManagerArgsCommand(c.Name, "purge", c.Args)
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpPurge, c.Args)
+}
+
+func (c *SubsystemHoldCmd) Run() error {
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpHold, c.Args)
+}
+
+func (c *SubsystemUnholdCmd) Run() error {
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpUnhold, c.Args)
+}
+
+func (c *SubsystemListUpgradableCmd) Run() error {
+	return genericPkgManagerCommand(c.Name, core.PkgManagerOpListUpgradable)
+}
+
+func (c *SubsystemRepoAddCmd) Run() error {
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpRepoAdd, c.Args)
+}
+
+func (c *SubsystemRepoRemoveCmd) Run() error {
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpRepoRemove, c.Args)
+}
+
+func (c *SubsystemOwnsCmd) Run() error {
+	return genericPkgManagerArgsCommand(c.Name, core.PkgManagerOpOwns, c.Args)
+}
+
+// stateResult describes a started or stopped subsystem in the structured
+// output.
+type stateResult struct {
+	Subsystem string
+	Running   bool
 }
 
 func (c *SubsystemStartCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	subSystem, err := core.LoadSubSystem(c.Name, false)
 	if err != nil {
 		return err
@@ -131,13 +497,20 @@ func (c *SubsystemStartCmd) Run() error {
 	Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.startingContainer"), subSystem.Name)
 	err = subSystem.Start()
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.startingContainer"), err)
+		return wrapError(err, "runtimeCommand.error.startingContainer")
 	}
-	Apx.Log.Info(Apx.LC.Get("runtimeCommand.info.startedContainer"))
-	return nil
+	return printResult(
+		stateResult{Subsystem: subSystem.Name, Running: true},
+		Apx.LC.Get("runtimeCommand.info.startedContainer"),
+	)
 }
 
 func (c *SubsystemStopCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	subSystem, err := core.LoadSubSystem(c.Name, false)
 	if err != nil {
 		return err
@@ -145,82 +518,162 @@ func (c *SubsystemStopCmd) Run() error {
 	Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.stoppingContainer"), subSystem.Name)
 	err = subSystem.Stop()
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.stoppingContainer"), err)
+		return wrapError(err, "runtimeCommand.error.stoppingContainer")
 	}
-	Apx.Log.Info(Apx.LC.Get("runtimeCommand.info.stoppedContainer"))
-	return nil
+	return printResult(
+		stateResult{Subsystem: subSystem.Name, Running: false},
+		Apx.LC.Get("runtimeCommand.info.stoppedContainer"),
+	)
+}
+
+// exportResult describes an exported or unexported application or binary
+// in the structured output.
+type exportResult struct {
+	Subsystem string
+	App       string `json:",omitempty"`
+	Bin       string `json:",omitempty"`
+	Exported  bool
 }
 
 func (c *SubsystemExportCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	subSystem, err := core.LoadSubSystem(c.Name, false)
 	if err != nil {
 		return err
 	}
 
 	if c.App == "" && c.Bin == "" {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.noAppNameOrBin"))
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("runtimeCommand.error.noAppNameOrBin"))
 	}
 
 	if c.App != "" && c.Bin != "" {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.sameAppOrBin"))
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("runtimeCommand.error.sameAppOrBin"))
 	}
 
 	if c.App != "" {
-		err := subSystem.ExportDesktopEntry(c.App)
+		err := withHistory(subSystemHistory(subSystem), func() error {
+			return subSystem.ExportDesktopEntry(c.App)
+		})
 		if err != nil {
-			return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.exportingApp"), err)
+			return wrapError(err, "runtimeCommand.error.exportingApp")
 		}
-		Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.exportedApp"), c.App)
+		return printResult(
+			exportResult{Subsystem: subSystem.Name, App: c.App, Exported: true},
+			fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.exportedApp"), c.App),
+		)
 	} else {
-		err := subSystem.ExportBin(c.Bin, c.BinOutput)
+		err := withHistory(subSystemHistory(subSystem), func() error {
+			return subSystem.ExportBin(c.Bin, c.BinOutput)
+		})
 		if err != nil {
-			return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.exportingBin"), err)
+			return wrapError(err, "runtimeCommand.error.exportingBin")
 		}
-		Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.exportedBin"), c.Bin)
+		_ = core.ClearCommandNotFoundCache()
+		return printResult(
+			exportResult{Subsystem: subSystem.Name, Bin: c.Bin, Exported: true},
+			fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.exportedBin"), c.Bin),
+		)
 	}
-	return nil
 }
 
 func (c *SubsystemUnexportCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	subSystem, err := core.LoadSubSystem(c.Name, false)
 	if err != nil {
 		return err
 	}
 
 	if c.App == "" && c.Bin == "" {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.noAppNameOrBin"))
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("runtimeCommand.error.noAppNameOrBin"))
 	}
 
 	if c.App != "" && c.Bin != "" {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.sameAppOrBin"))
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("runtimeCommand.error.sameAppOrBin"))
 	}
 
 	if c.App != "" {
-		err := subSystem.UnexportDesktopEntry(c.App)
+		err := withHistory(subSystemHistory(subSystem), func() error {
+			return subSystem.UnexportDesktopEntry(c.App)
+		})
 		if err != nil {
-			return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.unexportingApp"), err)
+			return wrapError(err, "runtimeCommand.error.unexportingApp")
 		}
-		Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.unexportedApp"), c.App)
+		return printResult(
+			exportResult{Subsystem: subSystem.Name, App: c.App},
+			fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.unexportedApp"), c.App),
+		)
 	} else {
-		err := subSystem.UnexportBin(c.Bin, c.BinOutput)
+		err := withHistory(subSystemHistory(subSystem), func() error {
+			return subSystem.UnexportBin(c.Bin, c.BinOutput)
+		})
 		if err != nil {
-			return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.unexportingBin"), err)
+			return wrapError(err, "runtimeCommand.error.unexportingBin")
 		}
-		Apx.Log.Infof(Apx.LC.Get("runtimeCommand.info.unexportedBin"), c.Bin)
+		_ = core.ClearCommandNotFoundCache()
+		return printResult(
+			exportResult{Subsystem: subSystem.Name, Bin: c.Bin},
+			fmt.Sprintf(Apx.LC.Get("runtimeCommand.info.unexportedBin"), c.Bin),
+		)
 	}
-	return nil
 }
 
 // Helpers
 
+// loadSubSystem loads the subsystem targeted by a command. The name is
+// empty when a top-level shortcut is used without a default subsystem.
+func loadSubSystem(name string) (*core.SubSystem, error) {
+	if name == "" {
+		return nil, newCommandError(errCodeNotFound, Apx.LC.Get("runtimeCommand.error.noDefaultSubsystem"))
+	}
+
+	return core.LoadSubSystem(name, false)
+}
+
+// applyGlobalFlags validates the flags set on the root command and
+// propagates them to core. It must be called before the command changes
+// anything, so that an unknown output format is reported before the
+// result it can't be printed in. The APX_ASSUME_YES environment variable
+// is already handled by settings, so the --yes flag can only enable the
+// non-interactive mode.
+func applyGlobalFlags() error {
+	root, ok := Apx.CLI.GetRoot().(*RootCmd)
+	if !ok {
+		return nil
+	}
+
+	switch root.Output {
+	case "", outputTable, outputJSON, outputYAML:
+	default:
+		return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("apx.errors.unknownOutput"), root.Output))
+	}
+
+	if root.AssumeYes {
+		core.SetAssumeYes(true)
+	}
+	return nil
+}
+
 func genericPkgManagerCommand(subsystemName string, action string) error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	subSystem, err := core.LoadSubSystem(subsystemName, false)
 	if err != nil {
 		return err
 	}
 	pkgManager, err := subSystem.Stack.GetPkgManager()
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.cantAccessPkgManager"), err)
+		return wrapError(err, "runtimeCommand.error.cantAccessPkgManager")
 	}
 
 	cmdStr, err := pkgManagerCommands(pkgManager, action)
@@ -229,21 +682,29 @@ func genericPkgManagerCommand(subsystemName string, action string) error {
 	}
 
 	finalArgs := pkgManager.GenCmd(cmdStr)
-	_, err = subSystem.Exec(false, false, finalArgs...)
+	output, err := runPkgManagerCommand(subSystem, action, finalArgs)
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.executingCommand"), err)
+		return wrapError(err, "runtimeCommand.error.executingCommand")
+	}
+	if structuredOutput() {
+		return printStructured(pkgManagerResult{Subsystem: subSystem.Name, Action: action, Output: output})
 	}
 	return nil
 }
 
 func genericPkgManagerArgsCommand(subsystemName string, action string, args []string) error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	subSystem, err := core.LoadSubSystem(subsystemName, false)
 	if err != nil {
 		return err
 	}
 	pkgManager, err := subSystem.Stack.GetPkgManager()
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.cantAccessPkgManager"), err)
+		return wrapError(err, "runtimeCommand.error.cantAccessPkgManager")
 	}
 
 	cmdStr, err := pkgManagerCommands(pkgManager, action)
@@ -252,36 +713,71 @@ func genericPkgManagerArgsCommand(subsystemName string, action string, args []st
 	}
 
 	finalArgs := pkgManager.GenCmd(cmdStr, args...)
-	_, err = subSystem.Exec(false, false, finalArgs...)
+	output, err := runPkgManagerCommand(subSystem, action, finalArgs)
 	if err != nil {
-		return fmt.Errorf(Apx.LC.Get("runtimeCommand.error.executingCommand"), err)
+		return wrapError(err, "runtimeCommand.error.executingCommand")
+	}
+	if structuredOutput() {
+		return printStructured(pkgManagerResult{Subsystem: subSystem.Name, Action: action, Output: output})
 	}
 	return nil
 }
 
+// pkgManagerResult describes a package manager command run through the
+// generic helpers in the structured output. Failures are reported as error
+// objects, which carry the captured output too.
+type pkgManagerResult struct {
+	Subsystem string
+	Action    string
+	Output    string
+	ExitCode  int
+}
+
+// runPkgManagerCommand executes the package manager command in the
+// subsystem, recording it in the history if the action changes its state.
+// Upgrades are recorded as transactions too, since they can add and remove
+// packages. With a structured output, the output of the package manager
+// is captured and returned instead of being printed.
+func runPkgManagerCommand(subSystem *core.SubSystem, action string, finalArgs []string) (string, error) {
+	var output string
+	run := func() error {
+		var err error
+		output, err = subSystem.Exec(structuredOutput(), false, finalArgs...)
+		return err
+	}
+
+	if !historyPkgManagerOps[action] {
+		err := run()
+		return output, err
+	}
+
+	if action == core.PkgManagerOpUpgrade {
+		upgrade := run
+		run = func() error {
+			return subSystem.RecordTransaction(core.TransactionUpgrade, nil, upgrade)
+		}
+	}
+
+	err := withHistory(subSystemHistory(subSystem), run)
+	if err == nil && action == core.PkgManagerOpUpgrade {
+		err = subSystem.MarkUpgraded()
+	}
+	return output, err
+}
+
 func pkgManagerCommands(pkgManager *core.PkgManager, command string) (string, error) {
-	switch command {
-	case "autoremove":
-		return pkgManager.CmdAutoRemove, nil
-	case "clean":
-		return pkgManager.CmdClean, nil
-	case "install":
-		return pkgManager.CmdInstall, nil
-	case "list":
-		return pkgManager.CmdList, nil
-	case "purge":
-		return pkgManager.CmdPurge, nil
-	case "remove":
-		return pkgManager.CmdRemove, nil
-	case "search":
-		return pkgManager.CmdSearch, nil
-	case "show":
-		return pkgManager.CmdShow, nil
-	case "update":
-		return pkgManager.CmdUpdate, nil
-	case "upgrade":
-		return pkgManager.CmdUpgrade, nil
-	default:
-		return "", fmt.Errorf(Apx.LC.Get("apx.errors.unknownCommand"), command)
+	cmdStr, err := pkgManager.GetCommand(command)
+	if err != nil {
+		return "", newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("apx.errors.unknownCommand"), command))
+	}
+
+	if !pkgManager.Supports(command) {
+		return "", newCommandError(errCodeUnsupported, fmt.Sprintf(Apx.LC.Get("runtimeCommand.error.unsupportedCommand"), pkgManager.Name, command))
 	}
+
+	if pkgManager.UsesDeprecatedModel() {
+		Apx.Log.Warn(Apx.LC.Get("apx.warnings.deprecatedModel"))
+	}
+
+	return cmdStr, nil
 }
@@ -0,0 +1,114 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"sync"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+type searchResult struct {
+	Package   string
+	Version   string
+	Subsystem string
+	Stack     string
+}
+
+func (c *SearchCmd) Run() error {
+	if len(c.Args) == 0 {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("search.error.noQuery"))
+	}
+
+	subSystems, err := core.ListSubSystems(false, false)
+	if err != nil {
+		return err
+	}
+
+	if c.Running {
+		running := []*core.SubSystem{}
+		for _, subSystem := range subSystems {
+			if subSystem.IsRunning() {
+				running = append(running, subSystem)
+			}
+		}
+		subSystems = running
+	}
+
+	var results []searchResult
+	var errs map[string]error
+	if c.Json || structuredOutput() {
+		results, errs = searchSubSystems(subSystems, c.Args)
+	} else {
+		spinner := startSpinner(Apx.LC.Get("search.info.searching"))
+		results, errs = searchSubSystems(subSystems, c.Args)
+		spinner.Stop()
+	}
+
+	for name, err := range errs {
+		Apx.Log.Errorf(Apx.LC.Get("search.error.searching"), name, err)
+	}
+
+	if c.Json || structuredOutput() {
+		return printStructured(results)
+	}
+
+	if len(results) == 0 {
+		Apx.Log.Info(Apx.LC.Get("search.info.noResults"))
+		return nil
+	}
+
+	Apx.Log.Infof(Apx.LC.Get("search.info.foundResults"), len(results))
+
+	headers := []string{Apx.LC.Get("search.labels.package"), Apx.LC.Get("search.labels.version"), Apx.LC.Get("search.labels.subsystem"), Apx.LC.Get("subsystems.labels.stack")}
+	var data [][]string
+	for _, result := range results {
+		data = append(data, []string{result.Package, result.Version, result.Subsystem, result.Stack})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+// searchSubSystems runs the search in all the given subsystems in parallel,
+// the results are merged keeping the subsystems order.
+func searchSubSystems(subSystems []*core.SubSystem, query []string) ([]searchResult, map[string]error) {
+	found := make([][]core.SearchResult, len(subSystems))
+	failures := make([]error, len(subSystems))
+
+	var wg sync.WaitGroup
+	for i, subSystem := range subSystems {
+		wg.Add(1)
+		go func(i int, subSystem *core.SubSystem) {
+			defer wg.Done()
+			found[i], failures[i] = subSystem.Search(query...)
+		}(i, subSystem)
+	}
+	wg.Wait()
+
+	results := []searchResult{}
+	errs := map[string]error{}
+	for i, subSystem := range subSystems {
+		if failures[i] != nil {
+			errs[subSystem.Name] = failures[i]
+			continue
+		}
+
+		for _, pkg := range found[i] {
+			results = append(results, searchResult{
+				Package:   pkg.Name,
+				Version:   pkg.Version,
+				Subsystem: subSystem.Name,
+				Stack:     subSystem.Stack.Name,
+			})
+		}
+	}
+
+	return results, errs
+}
@@ -10,9 +10,9 @@ package cli
 */
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/vanilla-os/apx/v3/core"
@@ -21,7 +21,7 @@ import (
 func (c *StacksListCmd) Run() error {
 	stacks := core.ListStacks()
 
-	if !c.Json {
+	if !c.Json && !structuredOutput() {
 		stacksCount := len(stacks)
 		if stacksCount == 0 {
 			fmt.Println(Apx.LC.Get("stacks.list.info.noStacks"))
@@ -41,12 +41,7 @@ func (c *StacksListCmd) Run() error {
 		}
 		Apx.CLI.Table(headers, data)
 	} else {
-		jsonStacks, err := json.MarshalIndent(stacks, "", "  ")
-		if err != nil {
-			return err
-		}
-
-		fmt.Println(string(jsonStacks))
+		return printStructured(stacks)
 	}
 
 	return nil
@@ -55,19 +50,38 @@ func (c *StacksListCmd) Run() error {
 func (c *StacksShowCmd) Run() error {
 	args := c.Args
 	if len(args) == 0 {
-		return fmt.Errorf("stack name required")
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.show.error.noName"))
 	}
 	stack, error := core.LoadStack(args[0])
 	if error != nil {
 		return error
 	}
 
+	if structuredOutput() {
+		return printStructured(stack)
+	}
+
 	headers := []string{"Property", "Value"}
 	data := [][]string{
 		{Apx.LC.Get("stacks.labels.name"), stack.Name},
 		{"Base", stack.Base},
 		{"Packages", strings.Join(stack.Packages, ", ")},
 		{"Package manager", stack.PkgManager},
+		{"Pull policy", stack.GetPullPolicy()},
+	}
+	if len(stack.Volumes) > 0 {
+		volumes := make([]string, len(stack.Volumes))
+		for i, volume := range stack.Volumes {
+			volumes[i] = volume.Spec()
+		}
+		data = append(data, []string{"Volumes", strings.Join(volumes, ", ")})
+	}
+	if len(stack.Env) > 0 {
+		env := make([]string, 0, len(stack.Env))
+		for _, key := range slices.Sorted(maps.Keys(stack.Env)) {
+			env = append(env, key+"="+stack.Env[key])
+		}
+		data = append(data, []string{"Environment", strings.Join(env, ", ")})
 	}
 	Apx.CLI.Table(headers, data)
 
@@ -75,6 +89,10 @@ func (c *StacksShowCmd) Run() error {
 }
 
 func (c *StacksNewCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
 
 	if c.Name == "" {
 		if !c.NoPrompt {
@@ -84,20 +102,17 @@ func (c *StacksNewCmd) Run() error {
 			}
 			c.Name = name
 			if c.Name == "" {
-				Apx.Log.Error(Apx.LC.Get("stacks.new.error.emptyName"))
-				return nil
+				return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.new.error.emptyName"))
 			}
 		} else {
-			Apx.Log.Error(Apx.LC.Get("stacks.new.error.noName"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.new.error.noName"))
 		}
 	}
 
 	ok := core.StackExists(c.Name)
 	if ok {
 		if ok {
-			Apx.Log.Errorf(Apx.LC.Get("stacks.new.error.alreadyExists"), c.Name)
-			return nil
+			return newCommandError(errCodeAlreadyExists, fmt.Sprintf(Apx.LC.Get("stacks.new.error.alreadyExists"), c.Name))
 		}
 	}
 
@@ -109,20 +124,17 @@ func (c *StacksNewCmd) Run() error {
 			}
 			c.BaseImage = base
 			if c.BaseImage == "" {
-				Apx.Log.Error(Apx.LC.Get("stacks.new.error.emptyBase"))
-				return nil
+				return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.new.error.emptyBase"))
 			}
 		} else {
-			Apx.Log.Error(Apx.LC.Get("stacks.new.error.noBase"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.new.error.noBase"))
 		}
 	}
 
 	if c.PkgManager == "" {
 		pkgManagers := core.ListPkgManagers()
 		if len(pkgManagers) == 0 {
-			Apx.Log.Error(Apx.LC.Get("stacks.new.error.noPkgManagers"))
-			return nil
+			return newCommandError(errCodeNotFound, Apx.LC.Get("stacks.new.error.noPkgManagers"))
 		}
 
 		var options []string
@@ -139,8 +151,7 @@ func (c *StacksNewCmd) Run() error {
 
 	ok = core.PkgManagerExists(c.PkgManager)
 	if !ok {
-		Apx.Log.Error(Apx.LC.Get("stacks.new.error.pkgManagerDoesNotExist"))
-		return nil
+		return newCommandError(errCodeNotFound, Apx.LC.Get("stacks.new.error.pkgManagerDoesNotExist"))
 	}
 
 	packagesArray := strings.Fields(c.Packages)
@@ -160,24 +171,32 @@ func (c *StacksNewCmd) Run() error {
 		}
 	}
 
+	err = core.ValidatePullPolicy(c.PullPolicy)
+	if err != nil {
+		return err
+	}
+
 	stack := core.NewStack(c.Name, c.BaseImage, packagesArray, c.PkgManager, false)
+	stack.PullPolicy = c.PullPolicy
 
-	err := stack.Save()
+	err = withHistory(core.HistoryEntry{Stack: stack.Name}, stack.Save)
 	if err != nil {
 		return err
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("stacks.new.info.success"), c.Name)
-
-	return nil
+	return printResult(stack, fmt.Sprintf(Apx.LC.Get("stacks.new.info.success"), c.Name))
 }
 
 func (c *StacksUpdateCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	args := c.Args
 	if c.Name == "" {
 		if len(args) != 1 || args[0] == "" {
-			Apx.Log.Error(Apx.LC.Get("stacks.update.error.noName"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.update.error.noName"))
 		}
 
 		c.Name = args[0]
@@ -189,8 +208,7 @@ func (c *StacksUpdateCmd) Run() error {
 	}
 
 	if stack.BuiltIn {
-		Apx.Log.Error(Apx.LC.Get("stacks.update.error.builtIn"))
-		os.Exit(126)
+		return newCommandError(errCodeReadOnly, Apx.LC.Get("stacks.update.error.builtIn"))
 	}
 
 	if c.BaseImage == "" {
@@ -204,8 +222,7 @@ func (c *StacksUpdateCmd) Run() error {
 				c.BaseImage = stack.Base
 			}
 		} else {
-			Apx.Log.Error(Apx.LC.Get("stacks.update.error.noBase"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.update.error.noBase"))
 		}
 	}
 
@@ -220,15 +237,13 @@ func (c *StacksUpdateCmd) Run() error {
 				c.PkgManager = stack.PkgManager
 			}
 		} else {
-			Apx.Log.Error(Apx.LC.Get("stacks.update.error.noPkgManager"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.update.error.noPkgManager"))
 		}
 	}
 
 	ok := core.PkgManagerExists(c.PkgManager)
 	if !ok {
-		Apx.Log.Error(Apx.LC.Get("stacks.update.error.pkgManagerDoesNotExist"))
-		return nil
+		return newCommandError(errCodeNotFound, Apx.LC.Get("stacks.update.error.pkgManagerDoesNotExist"))
 	}
 
 	if len(c.Packages) > 0 {
@@ -251,40 +266,51 @@ func (c *StacksUpdateCmd) Run() error {
 		}
 	}
 
+	if c.PullPolicy != "" {
+		err := core.ValidatePullPolicy(c.PullPolicy)
+		if err != nil {
+			return err
+		}
+		stack.PullPolicy = c.PullPolicy
+	}
+
 	stack.Base = c.BaseImage
 	stack.PkgManager = c.PkgManager
 
-	err := stack.Save()
+	err = withHistory(core.HistoryEntry{Stack: stack.Name}, stack.Save)
 	if err != nil {
 		return err
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("stacks.update.info.success"), c.Name)
-
-	return nil
+	return printResult(stack, fmt.Sprintf(Apx.LC.Get("stacks.update.info.success"), c.Name))
 }
 
 func (c *StacksRmCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Name == "" {
-		Apx.Log.Error(Apx.LC.Get("stacks.rm.error.noName"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.rm.error.noName"))
 	}
 
 	subSystems, _ := core.ListSubsystemForStack(c.Name)
 	if len(subSystems) > 0 {
-		Apx.Log.Errorf(Apx.LC.Get("stacks.rm.error.inUse"), len(subSystems))
-		headers := []string{Apx.LC.Get("subsystems.labels.name"), "Stack", Apx.LC.Get("subsystems.labels.status"), "Pkgs"}
-		var data [][]string
-		for _, subSystem := range subSystems {
-			data = append(data, []string{
-				subSystem.Name,
-				subSystem.Stack.Name,
-				subSystem.Status,
-				fmt.Sprintf("%d", len(subSystem.Stack.Packages)),
-			})
+		if !structuredOutput() {
+			headers := []string{Apx.LC.Get("subsystems.labels.name"), "Stack", Apx.LC.Get("subsystems.labels.status"), "Pkgs"}
+			var data [][]string
+			for _, subSystem := range subSystems {
+				data = append(data, []string{
+					subSystem.Name,
+					subSystem.Stack.Name,
+					subSystem.Status,
+					fmt.Sprintf("%d", len(subSystem.Stack.Packages)),
+				})
+			}
+			Apx.CLI.Table(headers, data)
 		}
-		Apx.CLI.Table(headers, data)
-		return fmt.Errorf(Apx.LC.Get("stacks.rm.error.inUse"), len(subSystems))
+		return newCommandError(errCodeInUse, fmt.Sprintf(Apx.LC.Get("stacks.rm.error.inUse"), len(subSystems)))
 	}
 
 	if !c.Force {
@@ -307,19 +333,22 @@ func (c *StacksRmCmd) Run() error {
 		return error
 	}
 
-	error = stack.Remove()
+	error = withHistory(core.HistoryEntry{Stack: stack.Name}, stack.Remove)
 	if error != nil {
 		return error
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("stacks.rm.info.success"), c.Name)
-	return nil
+	return printResult(stack, fmt.Sprintf(Apx.LC.Get("stacks.rm.info.success"), c.Name))
 }
 
 func (c *StacksExportCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Name == "" {
-		Apx.Log.Error(Apx.LC.Get("stacks.export.error.noName"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.export.error.noName"))
 	}
 
 	stack, error := core.LoadStack(c.Name)
@@ -327,36 +356,40 @@ func (c *StacksExportCmd) Run() error {
 		return error
 	}
 
-	if c.Output == "" {
-		Apx.Log.Error(Apx.LC.Get("stacks.export.error.noOutput"))
-		return nil
+	if c.File == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.export.error.noOutput"))
 	}
 
-	error = stack.Export(c.Output)
+	error = stack.Export(c.File)
 	if error != nil {
 		return error
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("stacks.export.info.success"), stack.Name, c.Output)
-	return nil
+	return printResult(
+		struct{ Stack, Path string }{stack.Name, c.File},
+		fmt.Sprintf(Apx.LC.Get("stacks.export.info.success"), stack.Name, c.File),
+	)
 }
 
 func (c *StacksImportCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Input == "" {
-		Apx.Log.Error(Apx.LC.Get("stacks.import.error.noInput"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("stacks.import.error.noInput"))
 	}
 
 	stack, error := core.LoadStackFromPath(c.Input)
 	if error != nil {
-		Apx.Log.Errorf(Apx.LC.Get("stacks.import.error.cannotLoad"), c.Input)
+		return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("stacks.import.error.cannotLoad"), c.Input))
 	}
 
-	error = stack.Save()
+	error = withHistory(core.HistoryEntry{Stack: stack.Name}, stack.Save)
 	if error != nil {
 		return error
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("stacks.import.info.success"), stack.Name)
-	return nil
+	return printResult(stack, fmt.Sprintf(Apx.LC.Get("stacks.import.info.success"), stack.Name))
 }
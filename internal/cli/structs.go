@@ -18,11 +18,29 @@ var Apx *app.App
 
 type RootCmd struct {
 	cli.Base
-	Version string
+	Version   string
+	AssumeYes bool   `flag:"long:yes, name:pr:apx.cmd.options.yes"`
+	Progress  string `flag:"long:progress, name:pr:apx.cmd.options.progress"`
+	Output    string `flag:"long:output, name:pr:apx.cmd.options.output"`
 
 	Stacks      StacksCmd      `cmd:"stacks" help:"pr:apx.cmd.stacks"`
 	Subsystems  SubsystemsCmd  `cmd:"subsystems" help:"pr:apx.cmd.subsystems"`
 	PkgManagers PkgManagersCmd `cmd:"pkgmanagers" help:"pr:apx.cmd.pkgmanagers"`
+	Outdated    OutdatedCmd    `cmd:"outdated" help:"pr:apx.cmd.outdated"`
+	AutoUpdate  AutoUpdateCmd  `cmd:"autoupdate" help:"pr:apx.cmd.autoupdate"`
+	Search      SearchCmd      `cmd:"search" help:"pr:apx.cmd.search"`
+	Which       WhichCmd       `cmd:"which" help:"pr:apx.cmd.which"`
+
+	CommandNotFound CommandNotFoundCmd `cmd:"command-not-found" help:"pr:apx.cmd.commandNotFound"`
+	History         HistoryCmd         `cmd:"history" help:"pr:apx.cmd.history"`
+	System          SystemCmd          `cmd:"system" help:"pr:apx.cmd.system"`
+	Images          ImagesCmd          `cmd:"images" help:"pr:apx.cmd.images"`
+
+	// Shortcuts targeting the default subsystem
+	Install SubsystemInstallCmd `cmd:"install" help:"pr:apx.cmd.install"`
+	Remove  SubsystemRemoveCmd  `cmd:"remove" help:"pr:apx.cmd.remove"`
+	Run     SubsystemRunCmd     `cmd:"run" help:"pr:apx.cmd.run"`
+	Enter   SubsystemEnterCmd   `cmd:"enter" help:"pr:apx.cmd.enter"`
 
 	DynamicSubsystems *map[string]*SubsystemCmd `cmd:"*" help:"apx.subsystem"`
 }
@@ -47,6 +65,20 @@ type SubsystemCmd struct {
 	AutoRemove SubsystemAutoRemoveCmd `cmd:"autoremove" help:"pr:apx.cmd.subsystem.autoremove"`
 	Clean      SubsystemCleanCmd      `cmd:"clean" help:"pr:apx.cmd.subsystem.clean"`
 	Purge      SubsystemPurgeCmd      `cmd:"purge" help:"pr:apx.cmd.subsystem.purge"`
+
+	Hold           SubsystemHoldCmd           `cmd:"hold" help:"pr:apx.cmd.subsystem.hold"`
+	Unhold         SubsystemUnholdCmd         `cmd:"unhold" help:"pr:apx.cmd.subsystem.unhold"`
+	ListUpgradable SubsystemListUpgradableCmd `cmd:"list-upgradable" help:"pr:apx.cmd.subsystem.listUpgradable"`
+	RepoAdd        SubsystemRepoAddCmd        `cmd:"repo-add" help:"pr:apx.cmd.subsystem.repoAdd"`
+	RepoRemove     SubsystemRepoRemoveCmd     `cmd:"repo-remove" help:"pr:apx.cmd.subsystem.repoRemove"`
+	Owns           SubsystemOwnsCmd           `cmd:"owns" help:"pr:apx.cmd.subsystem.owns"`
+
+	Undo SubsystemUndoCmd `cmd:"undo" help:"pr:apx.cmd.subsystem.undo"`
+	Diff SubsystemDiffCmd `cmd:"diff" help:"pr:apx.cmd.subsystem.diff"`
+	Sync SubsystemSyncCmd `cmd:"sync" help:"pr:apx.cmd.subsystem.sync"`
+
+	Mounts SubsystemMountsCmd `cmd:"mounts" help:"pr:apx.cmd.subsystem.mounts"`
+	Env    SubsystemEnvCmd    `cmd:"env" help:"pr:apx.cmd.subsystem.env"`
 }
 
 type SubsystemEnterCmd struct {
@@ -64,7 +96,7 @@ type SubsystemInstallCmd struct {
 	cli.Base
 	Name     string   `json:"-"`
 	NoExport bool     `flag:"short:n, long:no-export, name:pr:apx.cmd.subsystem.install.options.noExport"`
-	Args     []string `arg:"" optional:"" name:"packages" help:"pr:apx.arg.packages"`
+	Args     []string `arg:"" optional:"" name:"packages" help:"pr:apx.arg.packagesOrFiles"`
 }
 
 type SubsystemRemoveCmd struct {
@@ -144,6 +176,112 @@ type SubsystemPurgeCmd struct {
 	Args []string `arg:"" optional:"" name:"packages" help:"pr:apx.arg.packages"`
 }
 
+type SubsystemHoldCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"packages" help:"pr:apx.arg.packages"`
+}
+
+type SubsystemUnholdCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"packages" help:"pr:apx.arg.packages"`
+}
+
+type SubsystemListUpgradableCmd struct {
+	cli.Base
+	Name string `json:"-"`
+}
+
+type SubsystemRepoAddCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"repository" help:"pr:apx.arg.repository"`
+}
+
+type SubsystemRepoRemoveCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"repository" help:"pr:apx.arg.repository"`
+}
+
+type SubsystemOwnsCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"file" help:"pr:apx.arg.file"`
+}
+
+type SubsystemUndoCmd struct {
+	cli.Base
+	Name  string `json:"-"`
+	Force bool   `flag:"short:f, long:force, name:pr:apx.cmd.subsystem.undo.options.force"`
+}
+
+type SubsystemDiffCmd struct {
+	cli.Base
+	Name string `json:"-"`
+	Json bool   `flag:"short:j, long:json, name:pr:apx.cmd.subsystem.diff.options.json"`
+}
+
+type SubsystemSyncCmd struct {
+	cli.Base
+	Name  string `json:"-"`
+	Prune bool   `flag:"short:p, long:prune, name:pr:apx.cmd.subsystem.sync.options.prune"`
+	Force bool   `flag:"short:f, long:force, name:pr:apx.cmd.subsystem.sync.options.force"`
+}
+
+type SubsystemMountsCmd struct {
+	cli.Base
+	List   SubsystemMountsListCmd   `cmd:"list" help:"pr:apx.cmd.subsystem.mounts.list"`
+	Add    SubsystemMountsAddCmd    `cmd:"add" help:"pr:apx.cmd.subsystem.mounts.add"`
+	Remove SubsystemMountsRemoveCmd `cmd:"remove" help:"pr:apx.cmd.subsystem.mounts.remove"`
+}
+
+type SubsystemMountsListCmd struct {
+	cli.Base
+	Name string `json:"-"`
+	Json bool   `flag:"short:j, long:json, name:pr:apx.cmd.subsystem.mounts.list.options.json"`
+}
+
+type SubsystemMountsAddCmd struct {
+	cli.Base
+	Name     string   `json:"-"`
+	Args     []string `arg:"" optional:"" name:"paths" help:"pr:apx.arg.mount"`
+	ReadOnly bool     `flag:"short:r, long:read-only, name:pr:apx.cmd.subsystem.mounts.add.options.readOnly"`
+	Relabel  bool     `flag:"short:z, long:relabel, name:pr:apx.cmd.subsystem.mounts.add.options.relabel"`
+}
+
+type SubsystemMountsRemoveCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"destination" help:"pr:apx.arg.mountDestination"`
+}
+
+type SubsystemEnvCmd struct {
+	cli.Base
+	List  SubsystemEnvListCmd  `cmd:"list" help:"pr:apx.cmd.subsystem.env.list"`
+	Set   SubsystemEnvSetCmd   `cmd:"set" help:"pr:apx.cmd.subsystem.env.set"`
+	Unset SubsystemEnvUnsetCmd `cmd:"unset" help:"pr:apx.cmd.subsystem.env.unset"`
+}
+
+type SubsystemEnvListCmd struct {
+	cli.Base
+	Name string `json:"-"`
+	Json bool   `flag:"short:j, long:json, name:pr:apx.cmd.subsystem.env.list.options.json"`
+}
+
+type SubsystemEnvSetCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"variables" help:"pr:apx.arg.envVariables"`
+}
+
+type SubsystemEnvUnsetCmd struct {
+	cli.Base
+	Name string   `json:"-"`
+	Args []string `arg:"" optional:"" name:"names" help:"pr:apx.arg.envNames"`
+}
+
 // Stacks
 
 type StacksCmd struct {
@@ -174,6 +312,7 @@ type StacksNewCmd struct {
 	BaseImage  string `flag:"short:b, long:base, name:pr:apx.cmd.stacks.new.options.base"`
 	Packages   string `flag:"short:p, long:packages, name:pr:apx.cmd.stacks.new.options.packages"`
 	PkgManager string `flag:"short:k, long:pkg-manager, name:pr:apx.cmd.stacks.new.options.pkgManager"`
+	PullPolicy string `flag:"long:pull-policy, name:pr:apx.cmd.stacks.options.pullPolicy"`
 }
 
 type StacksUpdateCmd struct {
@@ -183,6 +322,7 @@ type StacksUpdateCmd struct {
 	BaseImage  string   `flag:"short:b, long:base, name:pr:apx.cmd.stacks.update.options.base"`
 	Packages   string   `flag:"short:p, long:packages, name:pr:apx.cmd.stacks.update.options.packages"`
 	PkgManager string   `flag:"short:k, long:pkg-manager, name:pr:apx.cmd.stacks.update.options.pkgManager"`
+	PullPolicy string   `flag:"long:pull-policy, name:pr:apx.cmd.stacks.options.pullPolicy"`
 	Args       []string `arg:"" optional:"" name:"stack" help:"pr:apx.arg.stack"`
 }
 
@@ -193,20 +333,31 @@ type PkgManagersShowCmd struct {
 
 type PkgManagersUpdateCmd struct {
 	cli.Base
-	NoPrompt   bool     `flag:"short:y, long:no-prompt, name:pr:apx.cmd.pkgmanagers.new.options.noPrompt"`
-	Name       string   `flag:"short:n, long:name, name:pr:apx.cmd.pkgmanagers.new.options.name"`
-	NeedSudo   bool     `flag:"short:S, long:need-sudo, name:pr:apx.cmd.pkgmanagers.new.options.needSudo"`
-	AutoRemove string   `flag:"short:a, long:autoremove, name:pr:apx.cmd.pkgmanagers.new.options.autoremove"`
-	Clean      string   `flag:"short:c, long:clean, name:pr:apx.cmd.pkgmanagers.new.options.clean"`
-	Install    string   `flag:"short:i, long:install, name:pr:apx.cmd.pkgmanagers.new.options.install"`
-	List       string   `flag:"short:l, long:list, name:pr:apx.cmd.pkgmanagers.new.options.list"`
-	Purge      string   `flag:"short:p, long:purge, name:pr:apx.cmd.pkgmanagers.new.options.purge"`
-	Remove     string   `flag:"short:r, long:remove, name:pr:apx.cmd.pkgmanagers.new.options.remove"`
-	Search     string   `flag:"short:s, long:search, name:pr:apx.cmd.pkgmanagers.new.options.search"`
-	Show       string   `flag:"short:w, long:show, name:pr:apx.cmd.pkgmanagers.new.options.show"`
-	Update     string   `flag:"short:u, long:update, name:pr:apx.cmd.pkgmanagers.new.options.update"`
-	Upgrade    string   `flag:"short:U, long:upgrade, name:pr:apx.cmd.pkgmanagers.new.options.upgrade"`
-	Args       []string `arg:"" optional:"" name:"pkgmanager" help:"pr:apx.arg.pkgmanager"`
+	NoPrompt   bool   `flag:"short:y, long:no-prompt, name:pr:apx.cmd.pkgmanagers.new.options.noPrompt"`
+	Name       string `flag:"short:n, long:name, name:pr:apx.cmd.pkgmanagers.new.options.name"`
+	NeedSudo   bool   `flag:"short:S, long:need-sudo, name:pr:apx.cmd.pkgmanagers.new.options.needSudo"`
+	AutoRemove string `flag:"short:a, long:autoremove, name:pr:apx.cmd.pkgmanagers.new.options.autoremove"`
+	Clean      string `flag:"short:c, long:clean, name:pr:apx.cmd.pkgmanagers.new.options.clean"`
+	Install    string `flag:"short:i, long:install, name:pr:apx.cmd.pkgmanagers.new.options.install"`
+	List       string `flag:"short:l, long:list, name:pr:apx.cmd.pkgmanagers.new.options.list"`
+	Purge      string `flag:"short:p, long:purge, name:pr:apx.cmd.pkgmanagers.new.options.purge"`
+	Remove     string `flag:"short:r, long:remove, name:pr:apx.cmd.pkgmanagers.new.options.remove"`
+	Search     string `flag:"short:s, long:search, name:pr:apx.cmd.pkgmanagers.new.options.search"`
+	Show       string `flag:"short:w, long:show, name:pr:apx.cmd.pkgmanagers.new.options.show"`
+	Update     string `flag:"short:u, long:update, name:pr:apx.cmd.pkgmanagers.new.options.update"`
+	Upgrade    string `flag:"short:U, long:upgrade, name:pr:apx.cmd.pkgmanagers.new.options.upgrade"`
+
+	InstallLocal         string   `flag:"long:install-local, name:pr:apx.cmd.pkgmanagers.new.options.installLocal"`
+	Hold                 string   `flag:"long:hold, name:pr:apx.cmd.pkgmanagers.new.options.hold"`
+	Unhold               string   `flag:"long:unhold, name:pr:apx.cmd.pkgmanagers.new.options.unhold"`
+	ListUpgradable       string   `flag:"long:list-upgradable, name:pr:apx.cmd.pkgmanagers.new.options.listUpgradable"`
+	RepoAdd              string   `flag:"long:repo-add, name:pr:apx.cmd.pkgmanagers.new.options.repoAdd"`
+	RepoRemove           string   `flag:"long:repo-remove, name:pr:apx.cmd.pkgmanagers.new.options.repoRemove"`
+	Owns                 string   `flag:"long:owns, name:pr:apx.cmd.pkgmanagers.new.options.owns"`
+	NonInteractiveFlag   string   `flag:"long:non-interactive-flag, name:pr:apx.cmd.pkgmanagers.new.options.nonInteractiveFlag"`
+	NonInteractiveEnv    string   `flag:"long:non-interactive-env, name:pr:apx.cmd.pkgmanagers.new.options.nonInteractiveEnv"`
+	NonInteractiveGlobal bool     `flag:"long:non-interactive-global, name:pr:apx.cmd.pkgmanagers.new.options.nonInteractiveGlobal"`
+	Args                 []string `arg:"" optional:"" name:"pkgmanager" help:"pr:apx.arg.pkgmanager"`
 }
 
 type StacksRmCmd struct {
@@ -217,8 +368,8 @@ type StacksRmCmd struct {
 
 type StacksExportCmd struct {
 	cli.Base
-	Name   string `flag:"short:n, long:name, name:pr:apx.cmd.stacks.export.options.name"`
-	Output string `flag:"short:o, long:output, name:pr:apx.cmd.stacks.export.options.output"`
+	Name string `flag:"short:n, long:name, name:pr:apx.cmd.stacks.export.options.name"`
+	File string `flag:"short:f, long:file, name:pr:apx.cmd.stacks.export.options.output"`
 }
 
 type StacksImportCmd struct {
@@ -234,6 +385,16 @@ type SubsystemsCmd struct {
 	New   SubsystemsNewCmd   `cmd:"new" help:"pr:apx.cmd.subsystems.new"`
 	Rm    SubsystemsRmCmd    `cmd:"rm" help:"pr:apx.cmd.subsystems.rm"`
 	Reset SubsystemsResetCmd `cmd:"reset" help:"pr:apx.cmd.subsystems.reset"`
+
+	Rebase SubsystemsRebaseCmd `cmd:"rebase" help:"pr:apx.cmd.subsystems.rebase"`
+	Rename SubsystemsRenameCmd `cmd:"rename" help:"pr:apx.cmd.subsystems.rename"`
+	Clone  SubsystemsCloneCmd  `cmd:"clone" help:"pr:apx.cmd.subsystems.clone"`
+
+	Default SubsystemsDefaultCmd `cmd:"default" help:"pr:apx.cmd.subsystems.default"`
+	Alias   SubsystemsAliasCmd   `cmd:"alias" help:"pr:apx.cmd.subsystems.alias"`
+
+	SetLimits SubsystemsSetLimitsCmd `cmd:"set-limits" help:"pr:apx.cmd.subsystems.setLimits"`
+	Inspect   SubsystemsInspectCmd   `cmd:"inspect" help:"pr:apx.cmd.subsystems.inspect"`
 }
 
 type SubsystemsListCmd struct {
@@ -247,6 +408,11 @@ type SubsystemsNewCmd struct {
 	Name  string `flag:"short:n, long:name, name:pr:apx.cmd.subsystem.new.options.name"`
 	Home  string `flag:"short:H, long:home, name:pr:apx.cmd.subsystem.new.options.home"`
 	Init  bool   `flag:"short:i, long:init, name:pr:apx.cmd.subsystem.new.options.init"`
+
+	CPUs     string `flag:"long:cpus, name:pr:apx.cmd.subsystem.limits.options.cpus"`
+	Memory   string `flag:"long:memory, name:pr:apx.cmd.subsystem.limits.options.memory"`
+	PIDs     string `flag:"long:pids-limit, name:pr:apx.cmd.subsystem.limits.options.pids"`
+	IOWeight string `flag:"long:io-weight, name:pr:apx.cmd.subsystem.limits.options.ioWeight"`
 }
 
 type SubsystemsRmCmd struct {
@@ -261,6 +427,53 @@ type SubsystemsResetCmd struct {
 	Force bool   `flag:"short:f, long:force, name:pr:apx.cmd.subsystem.reset.options.force"`
 }
 
+type SubsystemsRebaseCmd struct {
+	cli.Base
+	Name       string `flag:"short:n, long:name, name:pr:apx.cmd.subsystem.rebase.options.name"`
+	Stack      string `flag:"short:s, long:stack, name:pr:apx.cmd.subsystem.rebase.options.stack"`
+	Force      bool   `flag:"short:f, long:force, name:pr:apx.cmd.subsystem.rebase.options.force"`
+	AllowEmpty bool   `flag:"long:allow-empty, name:pr:apx.cmd.subsystem.rebase.options.allowEmpty"`
+}
+
+type SubsystemsRenameCmd struct {
+	cli.Base
+	Args []string `arg:"" optional:"" name:"names" help:"pr:apx.arg.rename"`
+}
+
+type SubsystemsInspectCmd struct {
+	cli.Base
+	Args []string `arg:"" optional:"" name:"name" help:"pr:apx.arg.subsystem"`
+	Json bool     `flag:"short:j, long:json, name:pr:apx.cmd.subsystems.inspect.options.json"`
+}
+
+type SubsystemsCloneCmd struct {
+	cli.Base
+	Args []string `arg:"" optional:"" name:"names" help:"pr:apx.arg.clone"`
+}
+
+type SubsystemsDefaultCmd struct {
+	cli.Base
+	Unset bool     `flag:"long:unset, name:pr:apx.cmd.subsystems.default.options.unset"`
+	Args  []string `arg:"" optional:"" name:"name" help:"pr:apx.arg.subsystem"`
+}
+
+type SubsystemsSetLimitsCmd struct {
+	cli.Base
+	Name     string `flag:"short:n, long:name, name:pr:apx.cmd.subsystem.setLimits.options.name"`
+	CPUs     string `flag:"long:cpus, name:pr:apx.cmd.subsystem.limits.options.cpus"`
+	Memory   string `flag:"long:memory, name:pr:apx.cmd.subsystem.limits.options.memory"`
+	PIDs     string `flag:"long:pids-limit, name:pr:apx.cmd.subsystem.limits.options.pids"`
+	IOWeight string `flag:"long:io-weight, name:pr:apx.cmd.subsystem.limits.options.ioWeight"`
+	Reset    bool   `flag:"long:reset, name:pr:apx.cmd.subsystem.setLimits.options.reset"`
+}
+
+type SubsystemsAliasCmd struct {
+	cli.Base
+	Remove bool     `flag:"short:r, long:remove, name:pr:apx.cmd.subsystems.alias.options.remove"`
+	Json   bool     `flag:"short:j, long:json, name:pr:apx.cmd.subsystems.alias.options.json"`
+	Args   []string `arg:"" optional:"" name:"alias" help:"pr:apx.arg.alias"`
+}
+
 // PkgManagers
 
 type PkgManagersCmd struct {
@@ -294,6 +507,17 @@ type PkgManagersNewCmd struct {
 	Show       string `flag:"short:w, long:show, name:pr:apx.cmd.pkgmanagers.new.options.show"`
 	Update     string `flag:"short:u, long:update, name:pr:apx.cmd.pkgmanagers.new.options.update"`
 	Upgrade    string `flag:"short:U, long:upgrade, name:pr:apx.cmd.pkgmanagers.new.options.upgrade"`
+
+	InstallLocal         string `flag:"long:install-local, name:pr:apx.cmd.pkgmanagers.new.options.installLocal"`
+	Hold                 string `flag:"long:hold, name:pr:apx.cmd.pkgmanagers.new.options.hold"`
+	Unhold               string `flag:"long:unhold, name:pr:apx.cmd.pkgmanagers.new.options.unhold"`
+	ListUpgradable       string `flag:"long:list-upgradable, name:pr:apx.cmd.pkgmanagers.new.options.listUpgradable"`
+	RepoAdd              string `flag:"long:repo-add, name:pr:apx.cmd.pkgmanagers.new.options.repoAdd"`
+	RepoRemove           string `flag:"long:repo-remove, name:pr:apx.cmd.pkgmanagers.new.options.repoRemove"`
+	Owns                 string `flag:"long:owns, name:pr:apx.cmd.pkgmanagers.new.options.owns"`
+	NonInteractiveFlag   string `flag:"long:non-interactive-flag, name:pr:apx.cmd.pkgmanagers.new.options.nonInteractiveFlag"`
+	NonInteractiveEnv    string `flag:"long:non-interactive-env, name:pr:apx.cmd.pkgmanagers.new.options.nonInteractiveEnv"`
+	NonInteractiveGlobal bool   `flag:"long:non-interactive-global, name:pr:apx.cmd.pkgmanagers.new.options.nonInteractiveGlobal"`
 }
 
 type PkgManagersRmCmd struct {
@@ -304,11 +528,126 @@ type PkgManagersRmCmd struct {
 
 type PkgManagersExportCmd struct {
 	cli.Base
-	Name   string `flag:"short:n, long:name, name:pr:apx.cmd.pkgmanagers.export.options.name"`
-	Output string `flag:"short:o, long:output, name:pr:apx.cmd.pkgmanagers.export.options.output"`
+	Name string `flag:"short:n, long:name, name:pr:apx.cmd.pkgmanagers.export.options.name"`
+	File string `flag:"short:f, long:file, name:pr:apx.cmd.pkgmanagers.export.options.output"`
 }
 
 type PkgManagersImportCmd struct {
 	cli.Base
 	Input string `flag:"short:i, long:input, name:pr:apx.cmd.pkgmanagers.import.options.input"`
 }
+
+// Outdated
+
+type OutdatedCmd struct {
+	cli.Base
+	Json bool `flag:"short:j, long:json, name:pr:apx.cmd.outdated.options.json"`
+}
+
+// AutoUpdate
+
+type AutoUpdateCmd struct {
+	cli.Base
+	Enable  AutoUpdateEnableCmd  `cmd:"enable" help:"pr:apx.cmd.autoupdate.enable"`
+	Disable AutoUpdateDisableCmd `cmd:"disable" help:"pr:apx.cmd.autoupdate.disable"`
+	Status  AutoUpdateStatusCmd  `cmd:"status" help:"pr:apx.cmd.autoupdate.status"`
+	Run     AutoUpdateRunCmd     `cmd:"run" help:"pr:apx.cmd.autoupdate.run"`
+}
+
+type AutoUpdateEnableCmd struct {
+	cli.Base
+	Schedule  string `flag:"short:s, long:schedule, name:pr:apx.cmd.autoupdate.options.schedule"`
+	Subsystem string `flag:"short:n, long:subsystem, name:pr:apx.cmd.autoupdate.options.subsystem"`
+	All       bool   `flag:"short:a, long:all, name:pr:apx.cmd.autoupdate.options.all"`
+}
+
+type AutoUpdateDisableCmd struct {
+	cli.Base
+}
+
+type AutoUpdateStatusCmd struct {
+	cli.Base
+	Json bool `flag:"short:j, long:json, name:pr:apx.cmd.autoupdate.status.options.json"`
+}
+
+type AutoUpdateRunCmd struct {
+	cli.Base
+	Subsystem string `flag:"short:n, long:subsystem, name:pr:apx.cmd.autoupdate.options.subsystem"`
+	All       bool   `flag:"short:a, long:all, name:pr:apx.cmd.autoupdate.options.all"`
+}
+
+// Search
+
+type SearchCmd struct {
+	cli.Base
+	Running bool     `flag:"short:r, long:running, name:pr:apx.cmd.search.options.running"`
+	Json    bool     `flag:"short:j, long:json, name:pr:apx.cmd.search.options.json"`
+	Args    []string `arg:"" optional:"" name:"query" help:"pr:apx.arg.query"`
+}
+
+// Which
+
+type WhichCmd struct {
+	cli.Base
+	Json bool     `flag:"short:j, long:json, name:pr:apx.cmd.which.options.json"`
+	Args []string `arg:"" optional:"" name:"command" help:"pr:apx.arg.command"`
+}
+
+// Command not found
+
+type CommandNotFoundCmd struct {
+	cli.Base
+	Shell   string   `flag:"short:s, long:shell, name:pr:apx.cmd.commandNotFound.options.shell"`
+	NoCache bool     `flag:"long:no-cache, name:pr:apx.cmd.commandNotFound.options.noCache"`
+	Search  bool     `flag:"long:search, name:pr:apx.cmd.commandNotFound.options.search"`
+	Json    bool     `flag:"short:j, long:json, name:pr:apx.cmd.commandNotFound.options.json"`
+	Args    []string `arg:"" optional:"" name:"command" help:"pr:apx.arg.command"`
+}
+
+// History
+
+type HistoryCmd struct {
+	cli.Base
+	Subsystem string `flag:"short:n, long:subsystem, name:pr:apx.cmd.history.options.subsystem"`
+	Json      bool   `flag:"short:j, long:json, name:pr:apx.cmd.history.options.json"`
+}
+
+// System
+
+type SystemCmd struct {
+	cli.Base
+	Df    SystemDfCmd    `cmd:"df" help:"pr:apx.cmd.system.df"`
+	Prune SystemPruneCmd `cmd:"prune" help:"pr:apx.cmd.system.prune"`
+}
+
+type SystemDfCmd struct {
+	cli.Base
+	Json     bool `flag:"short:j, long:json, name:pr:apx.cmd.system.df.options.json"`
+	RootFull bool `flag:"long:rootfull, name:pr:apx.cmd.system.options.rootFull"`
+}
+
+type SystemPruneCmd struct {
+	cli.Base
+	DryRun   bool `flag:"long:dry-run, name:pr:apx.cmd.system.prune.options.dryRun"`
+	Force    bool `flag:"short:f, long:force, name:pr:apx.cmd.system.prune.options.force"`
+	RootFull bool `flag:"long:rootfull, name:pr:apx.cmd.system.options.rootFull"`
+}
+
+// Images
+
+type ImagesCmd struct {
+	cli.Base
+	Load ImagesLoadCmd `cmd:"load" help:"pr:apx.cmd.images.load"`
+	Save ImagesSaveCmd `cmd:"save" help:"pr:apx.cmd.images.save"`
+}
+
+type ImagesLoadCmd struct {
+	cli.Base
+	Args []string `arg:"" optional:"" name:"file" help:"pr:apx.arg.imageArchive"`
+}
+
+type ImagesSaveCmd struct {
+	cli.Base
+	File string   `flag:"short:f, long:file, name:pr:apx.cmd.images.save.options.file"`
+	Args []string `arg:"" optional:"" name:"stack" help:"pr:apx.arg.stack"`
+}
@@ -10,8 +10,14 @@ package cli
 */
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/vanilla-os/apx/v3/core"
 )
@@ -22,7 +28,7 @@ func (c *SubsystemsListCmd) Run() error {
 		return err
 	}
 
-	if !c.Json {
+	if !c.Json && !structuredOutput() {
 		subSystemsCount := len(subSystems)
 		if subSystemsCount == 0 {
 			Apx.Log.Info(Apx.LC.Get("subsystems.list.info.noSubsystems"))
@@ -47,23 +53,21 @@ func (c *SubsystemsListCmd) Run() error {
 			return err
 		}
 	} else {
-		jsonSubSystems, err := json.MarshalIndent(subSystems, "", "  ")
-		if err != nil {
-			return err
-		}
-
-		fmt.Println(string(jsonSubSystems))
+		return printStructured(subSystems)
 	}
 
 	return nil
 }
 
 func (c *SubsystemsNewCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
 
 	stacks := core.ListStacks()
 	if len(stacks) == 0 {
-		Apx.Log.Error(Apx.LC.Get("subsystems.new.error.noStacks"))
-		return nil
+		return newCommandError(errCodeNotFound, Apx.LC.Get("subsystems.new.error.noStacks"))
 	}
 
 	if c.Name == "" {
@@ -73,8 +77,7 @@ func (c *SubsystemsNewCmd) Run() error {
 		}
 		c.Name = name
 		if c.Name == "" {
-			Apx.Log.Error(Apx.LC.Get("subsystems.new.error.emptyName"))
-			return nil
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.new.error.emptyName"))
 		}
 	}
 
@@ -91,47 +94,58 @@ func (c *SubsystemsNewCmd) Run() error {
 		c.Stack = selected
 	}
 
+	err = validateSubSystemName(c.Name)
+	if err != nil {
+		return err
+	}
+
 	checkSubSystem, err := core.LoadSubSystem(c.Name, false)
 	if err == nil {
-		Apx.Log.Errorf(Apx.LC.Get("subsystems.new.error.alreadyExists"), checkSubSystem.Name)
-		return nil
+		return newCommandError(errCodeAlreadyExists, fmt.Sprintf(Apx.LC.Get("subsystems.new.error.alreadyExists"), checkSubSystem.Name))
 	}
 
-	// Checking if name conflicts with existing commands.
-	// In SDK declarative approach, we might need a way to check root commands.
-	// Apx.CLI.GetRoot() returns the struct.
-	// We can iterate simple tags or just use known conflicts.
-	// For now we skip detailed conflict check as we are rebuilding.
-	// Or we could check if c.Name is a valid command name if feasible.
-
 	stack, err := core.LoadStack(c.Stack)
 	if err != nil {
 		return err
 	}
 
+	limits, err := parseLimits(c.CPUs, c.Memory, c.PIDs, c.IOWeight)
+	if err != nil {
+		return err
+	}
+
 	subSystem, err := core.NewSubSystem(c.Name, stack, c.Home, c.Init, false, false, false, true, "")
 	if err != nil {
 		return err
 	}
+	subSystem.Limits = limits
 
-	spinner := Apx.CLI.StartSpinner(fmt.Sprintf(Apx.LC.Get("subsystems.new.info.creatingSubsystem"), c.Name, c.Stack))
+	stopProgress, err := startProgress(fmt.Sprintf(Apx.LC.Get("subsystems.new.info.creatingSubsystem"), c.Name, c.Stack))
+	if err != nil {
+		return err
+	}
 
-	err = subSystem.Create()
+	err = withHistory(subSystemHistory(subSystem), subSystem.Create)
 	if err != nil {
-		spinner.Stop()
+		stopProgress()
 		return err
 	}
 
-	spinner.Stop()
-	Apx.Log.Infof(Apx.LC.Get("subsystems.new.info.success"), c.Name)
+	stopProgress()
 
-	return nil
+	// the cached lookups only cover the subsystems which existed then
+	_ = core.ClearCommandNotFoundCache()
+	return printResult(subSystem, fmt.Sprintf(Apx.LC.Get("subsystems.new.info.success"), c.Name))
 }
 
 func (c *SubsystemsRmCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Name == "" {
-		Apx.Log.Error(Apx.LC.Get("subsystems.rm.error.noName"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.rm.error.noName"))
 	}
 
 	if !c.Force {
@@ -151,20 +165,37 @@ func (c *SubsystemsRmCmd) Run() error {
 		return err
 	}
 
-	err = subSystem.Remove()
+	err = withHistory(subSystemHistory(subSystem), subSystem.Remove)
+	if err != nil {
+		return err
+	}
+
+	err = subSystem.RemoveMetadata()
+	if err != nil {
+		return err
+	}
+
+	err = subSystem.ForgetTransactions()
 	if err != nil {
 		return err
 	}
 
-	Apx.Log.Infof(Apx.LC.Get("subsystems.rm.info.success"), c.Name)
+	updateSubSystemPreferences(func(preferences *core.SubSystemPreferences) {
+		preferences.ForgetSubSystem(subSystem.Name)
+	})
 
-	return nil
+	_ = core.ClearCommandNotFoundCache()
+	return printResult(subSystem, fmt.Sprintf(Apx.LC.Get("subsystems.rm.info.success"), c.Name))
 }
 
 func (c *SubsystemsResetCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
 	if c.Name == "" {
-		Apx.Log.Error(Apx.LC.Get("subsystems.reset.error.noName"))
-		return nil
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.reset.error.noName"))
 	}
 
 	if !c.Force {
@@ -184,12 +215,537 @@ func (c *SubsystemsResetCmd) Run() error {
 		return err
 	}
 
-	err = subSystem.Reset()
+	err = withHistory(subSystemHistory(subSystem), subSystem.Reset)
+	if err != nil {
+		return err
+	}
+
+	_ = core.ClearCommandNotFoundCache()
+	return printResult(subSystem, fmt.Sprintf(Apx.LC.Get("subsystems.reset.info.success"), c.Name))
+}
+
+func (c *SubsystemsRebaseCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if c.Name == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.rebase.error.noName"))
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	stack := subSystem.Stack
+	if c.Stack != "" {
+		stack, err = core.LoadStack(c.Stack)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !c.Force {
+		confirm, _ := Apx.CLI.ConfirmAction(
+			fmt.Sprintf(Apx.LC.Get("subsystems.rebase.info.askConfirmation"), c.Name, stack.Name, stack.Base),
+			"y", "N",
+			false,
+		)
+		if !confirm {
+			Apx.Log.Info(Apx.LC.Get("apx.info.aborting"))
+			return nil
+		}
+	}
+
+	stopProgress, err := startProgress(fmt.Sprintf(Apx.LC.Get("subsystems.rebase.info.rebasing"), c.Name, stack.Name))
+	if err != nil {
+		return err
+	}
+
+	var result *core.RebaseResult
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		result, err = subSystem.Rebase(stack, c.AllowEmpty)
+		return err
+	})
+	stopProgress()
+	if errors.Is(err, core.ErrNoTransactionHistory) {
+		return newCommandError(errCodeConflict, fmt.Sprintf(Apx.LC.Get("subsystems.rebase.error.noHistory"), c.Name))
+	}
+	if err != nil {
+		return err
+	}
+
+	_ = core.ClearCommandNotFoundCache()
+	if structuredOutput() {
+		return printStructured(result)
+	}
+
+	Apx.Log.Infof(Apx.LC.Get("subsystems.rebase.info.restored"), len(result.Packages), len(result.Apps), len(result.Binaries))
+
+	if len(result.FailedPackages) > 0 {
+		Apx.Log.Errorf(Apx.LC.Get("subsystems.rebase.error.failedPackages"), strings.Join(result.FailedPackages, ", "))
+	}
+	if len(result.FailedApps) > 0 {
+		Apx.Log.Errorf(Apx.LC.Get("subsystems.rebase.error.failedApps"), strings.Join(result.FailedApps, ", "))
+	}
+	if len(result.FailedBinaries) > 0 {
+		Apx.Log.Errorf(Apx.LC.Get("subsystems.rebase.error.failedBinaries"), strings.Join(result.FailedBinaries, ", "))
+	}
+
+	Apx.Log.Infof(Apx.LC.Get("subsystems.rebase.info.success"), c.Name)
+
+	return nil
+}
+
+func (c *SubsystemsRenameCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) != 2 || c.Args[0] == "" || c.Args[1] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.rename.error.noNames"))
+	}
+	oldName, newName := c.Args[0], c.Args[1]
+
+	err = validateSubSystemName(newName)
+	if err != nil {
+		return err
+	}
+
+	subSystem, err := core.LoadSubSystem(oldName, false)
+	if err != nil {
+		return err
+	}
+
+	stopProgress, err := startProgress(fmt.Sprintf(Apx.LC.Get("subsystems.rename.info.renaming"), oldName, newName))
+	if err != nil {
+		return err
+	}
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.Rename(newName)
+	})
+	stopProgress()
+	if err != nil {
+		return err
+	}
+
+	updateSubSystemPreferences(func(preferences *core.SubSystemPreferences) {
+		preferences.RenameSubSystem(oldName, newName)
+	})
+
+	_ = core.ClearCommandNotFoundCache()
+	return printResult(subSystem, fmt.Sprintf(Apx.LC.Get("subsystems.rename.info.success"), oldName, newName))
+}
+
+func (c *SubsystemsCloneCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Args) != 2 || c.Args[0] == "" || c.Args[1] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.clone.error.noNames"))
+	}
+	source, destination := c.Args[0], c.Args[1]
+
+	err = validateSubSystemName(destination)
+	if err != nil {
+		return err
+	}
+
+	subSystem, err := core.LoadSubSystem(source, false)
+	if err != nil {
+		return err
+	}
+
+	stopProgress, err := startProgress(fmt.Sprintf(Apx.LC.Get("subsystems.clone.info.cloning"), source, destination))
 	if err != nil {
 		return err
 	}
+	var clone *core.SubSystem
+	err = withHistory(core.HistoryEntry{Subsystem: destination, Stack: subSystem.Stack.Name}, func() error {
+		clone, err = subSystem.Clone(destination)
+		return err
+	})
+	stopProgress()
+	if err != nil {
+		return err
+	}
+
+	_ = core.ClearCommandNotFoundCache()
+	return printResult(clone, fmt.Sprintf(Apx.LC.Get("subsystems.clone.info.success"), source, destination))
+}
+
+// validateSubSystemName checks the name is valid for a subsystem and does
+// not shadow one of the built-in commands, since subsystems are exposed as
+// top-level commands.
+func validateSubSystemName(name string) error {
+	err := core.ValidateSubSystemName(name)
+	if err != nil {
+		return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("subsystems.error.invalidName"), err))
+	}
 
-	Apx.Log.Infof(Apx.LC.Get("subsystems.reset.info.success"), c.Name)
+	if slices.Contains(builtInCommands(), strings.ToLower(name)) {
+		return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("subsystems.error.reservedName"), name))
+	}
 
 	return nil
 }
+
+// builtInCommands returns the names of the top-level commands, read from
+// the cmd tags of RootCmd, plus the ones added by the CLI builder.
+func builtInCommands() []string {
+	commands := []string{"help", "completion", "version", "man"}
+
+	rootType := reflect.TypeOf(RootCmd{})
+	for i := 0; i < rootType.NumField(); i++ {
+		name := rootType.Field(i).Tag.Get("cmd")
+		if name != "" && name != "*" {
+			commands = append(commands, name)
+		}
+	}
+
+	return commands
+}
+
+// WarnShadowedSubSystems warns about the subsystems named after a top-level
+// command, which can be created by older versions of apx and cannot be
+// reached through their name anymore.
+func WarnShadowedSubSystems(names []string) {
+	commands := builtInCommands()
+	for _, name := range names {
+		if slices.Contains(commands, strings.ToLower(name)) {
+			Apx.Log.Warnf(Apx.LC.Get("subsystems.warnings.shadowed"), name, name)
+		}
+	}
+}
+
+func (c *SubsystemsDefaultCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	preferences, err := core.LoadSubSystemPreferences()
+	if err != nil {
+		return err
+	}
+
+	if c.Unset {
+		preferences.Default = ""
+		err = preferences.Save()
+		if err != nil {
+			return err
+		}
+
+		return printResult(struct{ Default string }{}, Apx.LC.Get("subsystems.default.info.unset"))
+	}
+
+	if len(c.Args) == 0 {
+		if structuredOutput() {
+			return printStructured(struct{ Default string }{preferences.Default})
+		}
+
+		if preferences.Default == "" {
+			Apx.Log.Info(Apx.LC.Get("subsystems.default.info.none"))
+			return nil
+		}
+
+		fmt.Println(preferences.Default)
+		return nil
+	}
+
+	name := preferences.Resolve(c.Args[0])
+	subSystem, err := core.LoadSubSystem(name, false)
+	if err != nil {
+		return err
+	}
+
+	preferences.Default = subSystem.Name
+	err = preferences.Save()
+	if err != nil {
+		return err
+	}
+
+	return printResult(struct{ Default string }{subSystem.Name}, fmt.Sprintf(Apx.LC.Get("subsystems.default.info.success"), subSystem.Name))
+}
+
+func (c *SubsystemsAliasCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	preferences, err := core.LoadSubSystemPreferences()
+	if err != nil {
+		return err
+	}
+
+	if c.Remove {
+		if len(c.Args) != 1 {
+			return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.alias.error.noAlias"))
+		}
+
+		if _, ok := preferences.Aliases[c.Args[0]]; !ok {
+			return newCommandError(errCodeNotFound, fmt.Sprintf(Apx.LC.Get("subsystems.alias.error.notFound"), c.Args[0]))
+		}
+
+		delete(preferences.Aliases, c.Args[0])
+		err = preferences.Save()
+		if err != nil {
+			return err
+		}
+
+		return printResult(preferences.Aliases, fmt.Sprintf(Apx.LC.Get("subsystems.alias.info.removed"), c.Args[0]))
+	}
+
+	if len(c.Args) == 0 {
+		if c.Json || structuredOutput() {
+			return printStructured(preferences.Aliases)
+		}
+
+		if len(preferences.Aliases) == 0 {
+			Apx.Log.Info(Apx.LC.Get("subsystems.alias.info.noAliases"))
+			return nil
+		}
+
+		aliases := make([]string, 0, len(preferences.Aliases))
+		for alias := range preferences.Aliases {
+			aliases = append(aliases, alias)
+		}
+		slices.Sort(aliases)
+
+		headers := []string{Apx.LC.Get("subsystems.alias.labels.alias"), Apx.LC.Get("search.labels.subsystem")}
+		var data [][]string
+		for _, alias := range aliases {
+			data = append(data, []string{alias, preferences.Aliases[alias]})
+		}
+
+		return Apx.CLI.Table(headers, data)
+	}
+
+	if len(c.Args) != 2 || c.Args[0] == "" || c.Args[1] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.alias.error.noAlias"))
+	}
+	alias, name := c.Args[0], c.Args[1]
+
+	// aliases share the namespace of the subsystems and the commands
+	err = validateSubSystemName(alias)
+	if err != nil {
+		return err
+	}
+
+	if _, err := core.LoadSubSystem(alias, false); err == nil {
+		return newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("subsystems.alias.error.isSubsystem"), alias))
+	}
+
+	subSystem, err := core.LoadSubSystem(preferences.Resolve(name), false)
+	if err != nil {
+		return err
+	}
+
+	preferences.Aliases[alias] = subSystem.Name
+	err = preferences.Save()
+	if err != nil {
+		return err
+	}
+
+	return printResult(preferences.Aliases, fmt.Sprintf(Apx.LC.Get("subsystems.alias.info.success"), alias, subSystem.Name))
+}
+
+// updateSubSystemPreferences applies fn to the subsystem preferences and
+// saves them. Failures are only reported, since the subsystem operation
+// which triggered the update already succeeded.
+func updateSubSystemPreferences(fn func(preferences *core.SubSystemPreferences)) {
+	preferences, err := core.LoadSubSystemPreferences()
+	if err == nil {
+		fn(preferences)
+		err = preferences.Save()
+	}
+
+	if err != nil {
+		Apx.Log.Errorf(Apx.LC.Get("subsystems.error.updatingPreferences"), err)
+	}
+}
+
+func (c *SubsystemsSetLimitsCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	if c.Name == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.setLimits.error.noName"))
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Name, false)
+	if err != nil {
+		return err
+	}
+
+	limits, err := parseLimits(c.CPUs, c.Memory, c.PIDs, c.IOWeight)
+	if err != nil {
+		return err
+	}
+
+	// the given limits are added to the current ones, unless reset
+	if !c.Reset {
+		limits = subSystem.Limits.Merge(limits)
+	}
+
+	stopProgress, err := startProgress(fmt.Sprintf(Apx.LC.Get("subsystems.setLimits.info.applying"), c.Name))
+	if err != nil {
+		return err
+	}
+	err = withHistory(subSystemHistory(subSystem), func() error {
+		return subSystem.SetLimits(limits)
+	})
+	stopProgress()
+	if err != nil {
+		return err
+	}
+
+	return printResult(subSystem.Limits, fmt.Sprintf(Apx.LC.Get("subsystems.setLimits.info.success"), c.Name))
+}
+
+// parseLimits builds the resource limits from the command flags, empty
+// values leave the corresponding resource unlimited.
+func parseLimits(cpus string, memory string, pids string, ioWeight string) (core.ResourceLimits, error) {
+	limits := core.ResourceLimits{
+		CPUs:   cpus,
+		Memory: memory,
+	}
+
+	if pids != "" {
+		value, err := strconv.Atoi(pids)
+		if err != nil {
+			return limits, newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("subsystems.limits.error.invalid"), "pids-limit", pids))
+		}
+		limits.PIDs = value
+	}
+
+	if ioWeight != "" {
+		value, err := strconv.Atoi(ioWeight)
+		if err != nil {
+			return limits, newCommandError(errCodeInvalidArgument, fmt.Sprintf(Apx.LC.Get("subsystems.limits.error.invalid"), "io-weight", ioWeight))
+		}
+		limits.IOWeight = value
+	}
+
+	err := limits.Validate()
+	if err != nil {
+		return limits, wrapError(err, "subsystems.limits.error.validation")
+	}
+
+	return limits, nil
+}
+
+func (c *SubsystemsInspectCmd) Run() error {
+	if len(c.Args) != 1 || c.Args[0] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("subsystems.inspect.error.noName"))
+	}
+
+	subSystem, err := core.LoadSubSystem(c.Args[0], false)
+	if err != nil {
+		return err
+	}
+
+	info, err := subSystem.Inspect()
+	if err != nil {
+		return wrapError(err, "subsystems.inspect.error.inspecting")
+	}
+
+	if c.Json || structuredOutput() {
+		return printStructured(info)
+	}
+
+	unknown := Apx.LC.Get("subsystems.inspect.labels.unknown")
+	orDefault := func(value string, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+	hostDefault := Apx.LC.Get("subsystems.inspect.labels.hostDefault")
+
+	uptime := "-"
+	if info.Running {
+		uptime = info.Uptime().Round(time.Second).String()
+	}
+
+	installed := unknown
+	if info.InstalledPackages != nil {
+		installed = strconv.Itoa(*info.InstalledPackages)
+	}
+
+	lastUpgrade := unknown
+	if info.LastUpgrade != nil {
+		lastUpgrade = info.LastUpgrade.Local().Format(time.DateTime)
+	}
+
+	headers := []string{Apx.LC.Get("subsystems.inspect.labels.property"), Apx.LC.Get("subsystems.inspect.labels.value")}
+	data := [][]string{
+		{Apx.LC.Get("subsystems.labels.name"), info.Name},
+		{Apx.LC.Get("subsystems.inspect.labels.internalName"), info.InternalName},
+		{Apx.LC.Get("subsystems.labels.stack"), info.Stack},
+		{Apx.LC.Get("subsystems.inspect.labels.pkgManager"), info.PkgManager},
+		{Apx.LC.Get("subsystems.inspect.labels.packages"), strings.Join(info.Packages, ", ")},
+		{Apx.LC.Get("subsystems.inspect.labels.base"), info.Base},
+		{Apx.LC.Get("subsystems.inspect.labels.image"), info.Image},
+		{Apx.LC.Get("subsystems.inspect.labels.digest"), orDefault(info.ImageDigest, unknown)},
+		{Apx.LC.Get("subsystems.labels.status"), info.Status},
+		{Apx.LC.Get("subsystems.inspect.labels.uptime"), uptime},
+		{Apx.LC.Get("subsystems.inspect.labels.created"), info.Created.Local().Format(time.DateTime)},
+		{Apx.LC.Get("subsystems.inspect.labels.lastUpgrade"), lastUpgrade},
+		{Apx.LC.Get("subsystems.inspect.labels.rootfull"), strconv.FormatBool(info.IsRootfull)},
+		{Apx.LC.Get("subsystems.inspect.labels.unshared"), strconv.FormatBool(info.IsUnshared)},
+		{Apx.LC.Get("subsystems.inspect.labels.init"), strconv.FormatBool(info.HasInit)},
+		{Apx.LC.Get("subsystems.inspect.labels.nvidia"), strconv.FormatBool(info.HasNvidia)},
+		{Apx.LC.Get("subsystems.inspect.labels.home"), orDefault(info.Home, hostDefault)},
+		{Apx.LC.Get("subsystems.inspect.labels.hostname"), orDefault(info.Hostname, hostDefault)},
+		{Apx.LC.Get("subsystems.inspect.labels.containerSize"), formatSize(info.ContainerSize)},
+		{Apx.LC.Get("subsystems.inspect.labels.imageSize"), formatSize(info.ImageSize)},
+		{Apx.LC.Get("subsystems.inspect.labels.installed"), installed},
+		{Apx.LC.Get("subsystems.inspect.labels.exportedApps"), strings.Join(info.ExportedApps, ", ")},
+		{Apx.LC.Get("subsystems.inspect.labels.exportedBinaries"), strings.Join(info.ExportedBinaries, ", ")},
+	}
+
+	if info.Limits.IsSet() {
+		data = append(data, []string{Apx.LC.Get("subsystems.inspect.labels.limits"), strings.Join(info.Limits.EngineFlags(), " ")})
+	}
+	if len(info.Volumes) > 0 {
+		volumes := make([]string, len(info.Volumes))
+		for i, volume := range info.Volumes {
+			volumes[i] = volume.Spec()
+		}
+		data = append(data, []string{Apx.LC.Get("subsystems.inspect.labels.volumes"), strings.Join(volumes, ", ")})
+	}
+	if len(info.Env) > 0 {
+		env := make([]string, 0, len(info.Env))
+		for _, key := range slices.Sorted(maps.Keys(info.Env)) {
+			env = append(env, key+"="+info.Env[key])
+		}
+		data = append(data, []string{Apx.LC.Get("subsystems.inspect.labels.environment"), strings.Join(env, ", ")})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+// formatSize returns the size in bytes in a human readable format.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
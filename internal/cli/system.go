@@ -0,0 +1,180 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+func (c *SystemDfCmd) Run() error {
+	spinner := startSpinner(Apx.LC.Get("system.df.info.computing"))
+	usage, err := core.GetDiskUsage(c.RootFull)
+	spinner.Stop()
+	if err != nil {
+		return wrapError(err, "system.error.diskUsage")
+	}
+
+	if c.Json || structuredOutput() {
+		return printStructured(usage)
+	}
+
+	headers := []string{Apx.LC.Get("subsystems.labels.name"), Apx.LC.Get("subsystems.labels.stack"), Apx.LC.Get("subsystems.inspect.labels.containerSize"), Apx.LC.Get("subsystems.inspect.labels.home")}
+	var data [][]string
+	for _, subSystem := range usage.Subsystems {
+		home := Apx.LC.Get("subsystems.inspect.labels.hostDefault")
+		if subSystem.Home != "" {
+			home = fmt.Sprintf("%s (%s)", formatSize(subSystem.HomeSize), subSystem.Home)
+		}
+		data = append(data, []string{subSystem.Name, subSystem.Stack, formatSize(subSystem.ContainerSize), home})
+	}
+	if len(data) > 0 {
+		err = Apx.CLI.Table(headers, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	headers = []string{Apx.LC.Get("subsystems.inspect.labels.image"), Apx.LC.Get("system.labels.size"), Apx.LC.Get("system.labels.usedBy"), Apx.LC.Get("subsystems.labels.status")}
+	data = [][]string{}
+	for _, image := range usage.Images {
+		data = append(data, []string{imageLabel(image), formatSize(image.Size), imageUsedBy(image), imageStatus(image)})
+	}
+	if len(data) > 0 {
+		err = Apx.CLI.Table(headers, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	Apx.Log.Infof(Apx.LC.Get("system.df.info.total"), formatSize(usage.Total))
+	return nil
+}
+
+// pruneResult describes the images removed by prune, or the ones which
+// would be removed with --dry-run, in the structured output. Errors lists
+// the images which could not be removed.
+type pruneResult struct {
+	Images    []core.ImageDiskUsage
+	Reclaimed int64
+	DryRun    bool
+	Errors    []string `json:",omitempty"`
+}
+
+func (c *SystemPruneCmd) Run() error {
+	err := applyGlobalFlags()
+	if err != nil {
+		return err
+	}
+
+	spinner := startSpinner(Apx.LC.Get("system.df.info.computing"))
+	usage, err := core.GetDiskUsage(c.RootFull)
+	spinner.Stop()
+	if err != nil {
+		return wrapError(err, "system.error.diskUsage")
+	}
+
+	result := pruneResult{Images: usage.Prunable(), DryRun: c.DryRun}
+	if len(result.Images) == 0 {
+		return printResult(result, Apx.LC.Get("system.prune.info.nothingToPrune"))
+	}
+
+	labels := make([]string, len(result.Images))
+	for i, image := range result.Images {
+		result.Reclaimed += image.Size
+		labels[i] = fmt.Sprintf("%s (%s)", imageLabel(image), formatSize(image.Size))
+	}
+
+	msg := fmt.Sprintf(Apx.LC.Get("system.prune.info.images"), formatSize(result.Reclaimed)) + "\n\t - " + strings.Join(labels, "\n\t - ")
+	if c.DryRun {
+		return printResult(result, msg)
+	}
+
+	if !c.Force && !core.AssumeYes() {
+		confirm, err := confirmAction(msg + "\n" + Apx.LC.Get("system.prune.info.confirm"))
+		if err != nil {
+			return err
+		}
+		if !confirm {
+			Apx.Log.Info(Apx.LC.Get("apx.info.aborting"))
+			return nil
+		}
+	}
+
+	spinner = startSpinner(Apx.LC.Get("system.prune.info.pruning"))
+	var pruneErr error
+	_ = withHistory(core.HistoryEntry{}, func() error {
+		result.Images, pruneErr = core.PruneImages(result.Images)
+		return pruneErr
+	})
+	spinner.Stop()
+
+	result.Reclaimed = 0
+	for _, image := range result.Images {
+		result.Reclaimed += image.Size
+	}
+
+	if pruneErr == nil {
+		return printResult(result, fmt.Sprintf(Apx.LC.Get("system.prune.info.success"), formatSize(result.Reclaimed)))
+	}
+
+	if joined, ok := pruneErr.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	} else {
+		result.Errors = []string{pruneErr.Error()}
+	}
+
+	if structuredOutput() {
+		err = printStructured(result)
+		if err != nil {
+			return err
+		}
+	} else {
+		if len(result.Images) > 0 {
+			Apx.Log.Infof(Apx.LC.Get("system.prune.info.success"), formatSize(result.Reclaimed))
+		}
+		for _, msg := range result.Errors {
+			Apx.Log.Errorf(Apx.LC.Get("system.prune.error.pruning"), msg)
+		}
+	}
+
+	// the outcome was already reported
+	return newCommandError(errCodeUnknown, "")
+}
+
+// imageLabel returns the first name of the image, or its short ID for
+// untagged images.
+func imageLabel(image core.ImageDiskUsage) string {
+	if len(image.Names) > 0 {
+		return image.Names[0]
+	}
+	if len(image.ID) > 12 {
+		return image.ID[:12]
+	}
+	return image.ID
+}
+
+func imageUsedBy(image core.ImageDiskUsage) string {
+	if image.Snapshot {
+		return Apx.LC.Get("system.labels.snapshot")
+	}
+	return strings.Join(image.Stacks, ", ")
+}
+
+func imageStatus(image core.ImageDiskUsage) string {
+	if image.InUse {
+		return Apx.LC.Get("system.labels.inUse")
+	}
+	return Apx.LC.Get("system.labels.unused")
+}
@@ -0,0 +1,106 @@
+package cli
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Pietro di Caprio <pietro@fabricators.ltd>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description: Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"sync"
+
+	"github.com/vanilla-os/apx/v3/core"
+)
+
+type whichResult struct {
+	Subsystem string
+	Stack     string
+	Path      string
+	Package   string
+	Exported  bool
+}
+
+func (c *WhichCmd) Run() error {
+	if len(c.Args) != 1 || c.Args[0] == "" {
+		return newCommandError(errCodeInvalidArgument, Apx.LC.Get("which.error.noCommand"))
+	}
+	command := c.Args[0]
+
+	subSystems, err := core.ListSubSystems(false, false)
+	if err != nil {
+		return err
+	}
+
+	results := whichSubSystems(subSystems, command)
+
+	if c.Json || structuredOutput() {
+		return printStructured(results)
+	}
+
+	if len(results) == 0 {
+		Apx.Log.Infof(Apx.LC.Get("which.info.notFound"), command)
+		return nil
+	}
+
+	Apx.Log.Infof(Apx.LC.Get("which.info.found"), command, len(results))
+
+	headers := []string{Apx.LC.Get("search.labels.subsystem"), Apx.LC.Get("subsystems.labels.stack"), Apx.LC.Get("which.labels.path"), Apx.LC.Get("search.labels.package"), Apx.LC.Get("which.labels.exported")}
+	var data [][]string
+	for _, result := range results {
+		exported := Apx.LC.Get("apx.terminal.no")
+		if result.Exported {
+			exported = Apx.LC.Get("apx.terminal.yes")
+		}
+		data = append(data, []string{result.Subsystem, result.Stack, result.Path, result.Package, exported})
+	}
+
+	return Apx.CLI.Table(headers, data)
+}
+
+// whichSubSystems looks for the command in the PATH of each subsystem in
+// parallel, resolving the owning package and whether it is exported.
+func whichSubSystems(subSystems []*core.SubSystem, command string) []whichResult {
+	found := make([]*whichResult, len(subSystems))
+
+	var wg sync.WaitGroup
+	for i, subSystem := range subSystems {
+		wg.Add(1)
+		go func(i int, subSystem *core.SubSystem) {
+			defer wg.Done()
+
+			exported := subSystem.IsBinaryExported(command)
+			if _, ok := subSystem.ExportedPrograms[command]; ok {
+				exported = true
+			}
+
+			path, err := subSystem.Which(command)
+			if err != nil || path == "" {
+				return
+			}
+
+			// the owner is optional, not every package manager supports it
+			owner, _ := subSystem.Owner(path)
+
+			found[i] = &whichResult{
+				Subsystem: subSystem.Name,
+				Stack:     subSystem.Stack.Name,
+				Path:      path,
+				Package:   owner,
+				Exported:  exported,
+			}
+		}(i, subSystem)
+	}
+	wg.Wait()
+
+	results := []whichResult{}
+	for _, result := range found {
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+
+	return results
+}
@@ -10,9 +10,12 @@ package settings
 */
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/vanilla-os/sdk/pkg/v1/conf"
@@ -24,6 +27,10 @@ type Config struct {
 	DistroboxPath string `json:"distroboxPath"`
 	StorageDriver string `json:"storageDriver"`
 
+	// Behaviour
+	AssumeYes  bool   `json:"assumeYes"`
+	PullPolicy string `json:"pullPolicy"`
+
 	// Virtual
 	UserApxPath         string
 	ApxStoragePath      string
@@ -57,9 +64,43 @@ func GetApxDefaultConfig() (*Config, error) {
 		distroboxPath,
 		config.StorageDriver,
 	)
+
+	// APX_ASSUME_YES overrides the configuration file, so that scripts and
+	// CI jobs can run apx non-interactively without touching apx.json
+	Cnf.AssumeYes = config.AssumeYes
+	if assumeYes, err := strconv.ParseBool(os.Getenv("APX_ASSUME_YES")); err == nil {
+		Cnf.AssumeYes = assumeYes
+	}
+
+	Cnf.PullPolicy = config.PullPolicy
+	source := "pullPolicy"
+	if pullPolicy := os.Getenv("APX_PULL_POLICY"); pullPolicy != "" {
+		Cnf.PullPolicy = pullPolicy
+		source = "APX_PULL_POLICY"
+	}
+
+	err = ValidatePullPolicy(Cnf.PullPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
 	return Cnf, nil
 }
 
+// ErrInvalidPullPolicy is returned when the pull policy is not one of
+// "always", "missing" or "never".
+var ErrInvalidPullPolicy = errors.New("invalid pull policy")
+
+// ValidatePullPolicy checks the pull policy is one of the supported ones.
+// An empty policy means the default one.
+func ValidatePullPolicy(policy string) error {
+	switch policy {
+	case "", "always", "missing", "never":
+		return nil
+	}
+	return fmt.Errorf("%w %q, use always, missing or never", ErrInvalidPullPolicy, policy)
+}
+
 func NewApxConfig(apxPath, distroboxPath, storageDriver string) *Config {
 	userDataDir, err := UserDataDir()
 	if err != nil {
@@ -0,0 +1,50 @@
+package settings
+
+/*	License: GPLv3
+	Authors:
+		Mirko Brombin <brombin94@gmail.com>
+		Vanilla OS Contributors <https://github.com/vanilla-os/>
+	Copyright: 2024
+	Description:
+		Apx is a wrapper around multiple package managers to install packages and run commands inside a managed container.
+*/
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetApxDefaultConfigPullPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		wantErr bool
+	}{
+		{"", false},
+		{"always", false},
+		{"missing", false},
+		{"never", false},
+		{"nevr", true},
+		{"Never", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("APX_PULL_POLICY", tt.policy)
+
+			cnf, err := GetApxDefaultConfig()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPullPolicy) {
+					t.Errorf("GetApxDefaultConfig() error = %v, want ErrInvalidPullPolicy", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cnf.PullPolicy != tt.policy {
+				t.Errorf("PullPolicy = %q, want %q", cnf.PullPolicy, tt.policy)
+			}
+		})
+	}
+}